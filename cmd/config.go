@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+)
+
+// DefaultConfigReloadInterval is the interval to check whether the config file changed.
+const DefaultConfigReloadInterval = 10 * time.Second
+
+// ConfigFile is a yaml file whose keys are the names of flags. The flags set in the command
+// line take precedence over it.
+type ConfigFile struct {
+	path string
+
+	flags *pflag.FlagSet
+
+	// explicit are the flags set in the command line.
+	explicit map[string]struct{}
+
+	content []byte
+
+	values map[string]string
+}
+
+// NewConfigFile must be called after the command line is parsed.
+func NewConfigFile(path string, flags *pflag.FlagSet) *ConfigFile {
+	explicit := make(map[string]struct{})
+	flags.Visit(func(flag *pflag.Flag) {
+		explicit[flag.Name] = struct{}{}
+	})
+
+	return &ConfigFile{
+		path:     path,
+		flags:    flags,
+		explicit: explicit,
+	}
+}
+
+// Load reads the config file and applies all the options in it.
+func (c *ConfigFile) Load() error {
+	content, values, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	for name, value := range values {
+		if err := c.set(name, value); err != nil {
+			return err
+		}
+	}
+
+	c.content = content
+	c.values = values
+	return nil
+}
+
+// Watch checks the config file periodically, applies the changes of reloadable options and then
+// calls onReload. The changes of other options take effect after restart.
+func (c *ConfigFile) Watch(interval time.Duration, reloadable []string, onReload func(), stop <-chan struct{}) {
+	reloadableSet := make(map[string]struct{}, len(reloadable))
+	for _, name := range reloadable {
+		reloadableSet[name] = struct{}{}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		content, values, err := c.read()
+		if err != nil {
+			logger.Errorf("Reload config file %s fail, err %v.", c.path, err)
+			continue
+		}
+		if reflect.DeepEqual(content, c.content) {
+			continue
+		}
+
+		changed := false
+		for name := range union(c.values, values) {
+			value, exist := values[name]
+			if exist && value == c.values[name] {
+				continue
+			}
+			if _, ok := reloadableSet[name]; !ok {
+				logger.Warnf("Option %s changed in config file is rejected, because it is not reloadable "+
+					"and takes effect after restart.", name)
+				continue
+			}
+			if _, ok := c.explicit[name]; ok {
+				logger.Warnf("Option %s changed in config file is ignored, because it is set in the command line.", name)
+				continue
+			}
+			if !exist {
+				// The option removed from config file is reset to default.
+				value = c.flags.Lookup(name).DefValue
+			}
+			if err := c.set(name, value); err != nil {
+				logger.Errorf("Reload option %s fail, err %v.", name, err)
+				continue
+			}
+			logger.Infof("Reload option %s=%q.", name, value)
+			changed = true
+		}
+
+		c.content = content
+		c.values = values
+		if changed {
+			onReload()
+		}
+	}
+}
+
+func (c *ConfigFile) read() ([]byte, map[string]string, error) {
+	content, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if c.flags.Lookup(name) == nil {
+			return nil, nil, fmt.Errorf("unknown option %s in config file", name)
+		}
+		str, err := formatValue(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid option %s in config file, err %v", name, err)
+		}
+		values[name] = str
+	}
+
+	return content, values, nil
+}
+
+// set changes the flag unless it is set in the command line.
+func (c *ConfigFile) set(name, value string) error {
+	if _, exist := c.explicit[name]; exist {
+		return nil
+	}
+
+	flag := c.flags.Lookup(name)
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		var items []string
+		if value != "" {
+			items = strings.Split(value, ",")
+		}
+		return sliceValue.Replace(items)
+	}
+
+	return flag.Value.Set(value)
+}
+
+// formatValue converts the yaml value into the string form of flag. The lists are joined by comma.
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := formatValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("not supported value %v", value)
+	}
+}
+
+func union(a, b map[string]string) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for key := range a {
+		keys[key] = struct{}{}
+	}
+	for key := range b {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/nacos-group/nacos-sdk-go/common/constant"
 	"github.com/spf13/cobra"
@@ -14,15 +15,22 @@ import (
 	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
 )
 
+// reloadableOptions are the options whose changes in config file are applied at runtime.
+var reloadableOptions = []string{
+	"log_output_level", "maxRetry", "serviceSelector", "includeNamespaces", "excludeNamespaces",
+}
+
 var (
 	options       bootstrap.Options
 	loggerOptions = logger.DefaultOptions()
+	configFile    string
+	config        *cmd.ConfigFile
 
 	rootCmd = &cobra.Command{
 		Use:     "nacos-k8s-sync",
 		Short:   "Sync service information between k8s and nacos.",
 		Args:    cobra.NoArgs,
-		PreRunE: configureLogging,
+		PreRunE: loadConfig,
 		RunE: func(c *cobra.Command, args []string) error {
 			cmd.PrintFlags(c.Flags())
 
@@ -33,12 +41,36 @@ var (
 			}
 			server.Run(stop)
 
+			if config != nil {
+				go config.Watch(cmd.DefaultConfigReloadInterval, reloadableOptions, func() { reload(server) }, stop)
+			}
+
 			cmd.WaitSignal(stop)
 			return nil
 		},
 	}
 )
 
+func loadConfig(c *cobra.Command, args []string) error {
+	if configFile != "" {
+		config = cmd.NewConfigFile(configFile, c.Flags())
+		if err := config.Load(); err != nil {
+			return err
+		}
+	}
+
+	return configureLogging(c, args)
+}
+
+// reload applies the options changed in config file at runtime.
+func reload(server *bootstrap.Server) {
+	if err := logger.SetOutputLevel(loggerOptions.OutputLevel); err != nil {
+		logger.Errorf("Reload log level fail, err %v.", err)
+	}
+	model.SetMaxRetry(options.MaxRetry)
+	server.ReloadKubeOptions(options.KubeOptions)
+}
+
 func configureLogging(_ *cobra.Command, _ []string) error {
 	if err := logger.Configure(loggerOptions); err != nil {
 		return err
@@ -47,13 +79,38 @@ func configureLogging(_ *cobra.Command, _ []string) error {
 }
 
 func init() {
-	// TODO Use configmap to avoid so many cmd line args.
+	rootCmd.Flags().StringVar(&configFile, "config", "",
+		"Specify the yaml config file whose keys are the names of flags, and the flags set in the command line "+
+			"take precedence over it. Only the changes of "+strings.Join(reloadableOptions, ", ")+" are applied "+
+			"at runtime, and the changes of the other keys are rejected with a warning until restart. The services "+
+			"affected by the changed filters are resynced, but the webhook keeps the filters until restart.")
+
 	rootCmd.Flags().StringVar(&options.KubeOptions.KubeConfig, "kubeconfig", "",
 		"Use a Kubernetes configuration file instead of in-cluster configuration.")
 
 	rootCmd.Flags().StringVarP(&options.KubeOptions.WatchedNamespace, "appNamespace", "a", v1.NamespaceAll,
 		"Specify the namespace in where the service source should be synced to nacos.")
 
+	rootCmd.Flags().StringVar(&options.KubeOptions.LabelSelector, "serviceSelector", "",
+		"Specify the label selector which restricts the services and endpoints watched and synced to nacos.")
+
+	rootCmd.Flags().StringSliceVar(&options.KubeOptions.IncludeNamespaces, "includeNamespaces", nil,
+		"Specify the namespaces whose services are synced to nacos. All namespaces are synced if it is empty.")
+
+	rootCmd.Flags().StringSliceVar(&options.KubeOptions.ExcludeNamespaces, "excludeNamespaces", nil,
+		"Specify the namespaces whose services are never watched and synced to nacos.")
+
+	rootCmd.Flags().StringSliceVar(&options.KubeOptions.RemoteClusters, "remoteClusters", nil,
+		"Specify the remote k8s clusters synced to nacos too, formatted as clusterID=kubeconfig "+
+			"or clusterID=kubeconfig#context. The kubeconfigs are loaded at startup, so restart syncer after "+
+			"changing them.")
+
+	rootCmd.Flags().StringVar(&options.KubeOptions.RemoteSecretNamespace, "remoteSecretNamespace", "",
+		"Specify the namespace of the secrets labeled by <annotationPrefix>/remote-cluster=true, such as "+
+			"nacos.io/remote-cluster=true, whose data are the kubeconfigs of remote clusters keyed by the cluster id. "+
+			"The secrets are loaded at startup and not watched, so restart syncer after adding, changing or "+
+			"removing them.")
+
 	rootCmd.Flags().StringVar(&options.NacosOptions.Namespace, "nacosNamespace", constant.DEFAULT_NAMESPACE_ID,
 		"Specify the namespace to which the service in naocs should be stored.")
 
@@ -64,6 +121,115 @@ func init() {
 	rootCmd.Flags().Uint64Var(&options.NacosOptions.ServerPort, "serverPort", 0,
 		"serverPort are explicitly specified to be used when the client connects to nacos.")
 
+	rootCmd.Flags().BoolVar(&options.SyncOptions.Ephemeral, "ephemeral", true,
+		"Register instances as ephemeral by default. Persistent instances are fully owned by the syncer, and with "+
+			"identityMeta the ones whose sources were deleted while it was down are unregistered at startup.")
+
+	rootCmd.Flags().StringVar((*string)(&options.SyncOptions.NotReadyPolicy), "notReadyPolicy", "",
+		"Specify how to register the not ready addresses which can be omit, unhealthy, or disabled. "+
+			"By default, it is unhealthy for persistent instances and omit for ephemeral instances. "+
+			"Unhealthy ephemeral instances are registered as disabled, because nacos resets their health by heartbeat.")
+
+	rootCmd.Flags().StringVar((*string)(&options.SyncOptions.MetadataPolicy), "metadataPolicy",
+		string(model.MetadataOverwrite),
+		"Specify how the metadata of instances are merged with the ones set outside the syncer, which can be "+
+			"overwrite, merge, or preserve-foreign-keys.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.Guard.ServiceThreshold, "deregisterServiceThreshold", "",
+		"Specify the most instances of a service deregistered at once, such as 10 or 50%. The deregistration "+
+			"above it is refused or delayed, unless the service is annotated to allow mass deregistration.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.Guard.ClusterThreshold, "deregisterClusterThreshold", "",
+		"Specify the most instances of all services deregistered within a minute, such as 100 or 20%. "+
+			"It limits the unregistrations of whole services too, which are retried after the minute.")
+
+	rootCmd.Flags().DurationVar(&options.SyncOptions.Guard.Delay, "deregisterDelay", 0,
+		"Specify how long the deregistration above the thresholds is delayed before being allowed. "+
+			"Zero means that it is refused until being allowed manually.")
+
+	rootCmd.Flags().DurationVar(&options.SyncOptions.DrainGracePeriod, "drainGracePeriod", 0,
+		"Specify how long the instances of terminating pods are drained before being unregistered. "+
+			"Zero means that they are unregistered immediately.")
+
+	rootCmd.Flags().StringVar((*string)(&options.SyncOptions.DrainMode), "drainMode", string(model.DrainDisable),
+		"Specify how to drain the instances of terminating pods which can be disable or weight.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.ClusterFromTopology, "clusterFromTopology", false,
+		"Derive the nacos cluster of instances from the topology zone of nodes if the cluster is not specified.")
+
+	rootCmd.Flags().StringSliceVar(&options.SyncOptions.PodLabelsAsMetadata, "podLabelsAsMeta", nil,
+		"Specify the keys of pod labels which are copied into the metadata of instances.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.ClusterID, "clusterID", "",
+		"Specify the id of the k8s cluster which is recorded in the metadata of instances.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.IdentityMetadata, "identityMeta", true,
+		"Add the k8s identity such as namespace, service, pod, node and cluster into the metadata of instances. "+
+			"It is required to import nacos services into k8s services.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.SyncIngress, "syncIngress", false,
+		"Sync the annotated ingresses of networking.k8s.io/v1 to nacos.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.SyncHTTPRoute, "syncHTTPRoute", false,
+		"Sync the annotated http routes of gateway api to nacos.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.SyncPod, "syncPod", false,
+		"Sync the annotated pods without k8s service to nacos.")
+
+	rootCmd.Flags().StringSliceVar(&options.SyncOptions.IstioServices, "istioServices", nil,
+		"Specify the nacos services formatted as name or name@@group which are converted into "+
+			"istio service entries and workload entries when syncing to k8s.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.IstioNamespace, "istioNamespace", "",
+		"Specify the namespace where the istio resources converted from nacos services are created.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.IstioHostSuffix, "istioHostSuffix", "nacos",
+		"Specify the suffix of the hosts of istio service entries, which are named as name.group.suffix.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.MCS, "mcs", false,
+		"Whether to act as the registry of multi-cluster services api, which registers the services "+
+			"exported by ServiceExport and imports the ones of other clusters as ServiceImport. "+
+			"Both directions and the cluster id are required.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.MCSGroup, "mcsGroup", "MCS_GROUP",
+		"Specify the nacos group of the services of multi-cluster services api.")
+
+	rootCmd.Flags().BoolVar(&options.SyncOptions.ServiceSyncCRD, "serviceSyncCRD", false,
+		"Sync the services referenced by NacosServiceSync custom resources, whose spec takes "+
+			"precedence over the annotations. The CRD must be installed.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.ServiceNameTemplate, "serviceNameTemplate", "",
+		"Specify the go template to generate the name of nacos service from the k8s object, such as "+
+			"{{.Namespace}}-{{.Name}}. The name annotation takes precedence. Default is the name of object.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.GroupTemplate, "groupTemplate", "",
+		"Specify the go template to generate the group of nacos service from the k8s object, such as "+
+			"{{.Labels.team}}. The group annotation takes precedence.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.RulesDataID, "rulesDataId", "",
+		"Specify the data id of nacos config which holds the sync rules, such as namespace mapping, filters, CEL rules, "+
+			"name templates and default metadata. The services are resynced when the rules change.")
+
+	rootCmd.Flags().StringVar(&options.SyncOptions.RulesGroup, "rulesGroup", constant.DEFAULT_GROUP,
+		"Specify the group of nacos config which holds the sync rules.")
+
+	rootCmd.Flags().IntVar(&options.MaxRetry, "maxRetry", model.DefaultMaxRetry,
+		"Specify the times to retry a failed sync task.")
+
+	rootCmd.Flags().StringVar(&options.AnnotationPrefix, "annotationPrefix", model.DefaultAnnotationPrefix,
+		"Specify the prefix of the keys of annotations, such as nacos.io in nacos.io/service-sync. "+
+			"The keys with the default prefix are still accepted as deprecated aliases.")
+
+	rootCmd.Flags().IntVar(&options.WebhookOptions.Port, "webhookPort", 0,
+		"Specify the port of the webhook which validates the nacos annotations of services. "+
+			"Zero means that the webhook is disabled.")
+
+	rootCmd.Flags().StringVar(&options.WebhookOptions.CertFile, "webhookCertFile", "",
+		"Specify the tls cert file of the webhook.")
+
+	rootCmd.Flags().StringVar(&options.WebhookOptions.KeyFile, "webhookKeyFile", "",
+		"Specify the tls key file of the webhook.")
+
 	rootCmd.Flags().StringVar((*string)(&options.Direction), "direction", string(model.ToNacos),
 		"Specify the direction of sync which can be to-nacos, to-k8s, or both")
 
@@ -3,6 +3,7 @@ module github.com/nacos-group/nacos-k8s-sync
 go 1.15
 
 require (
+	github.com/google/cel-go v0.12.6
 	github.com/hashicorp/go-multierror v1.1.0
 	github.com/nacos-group/nacos-sdk-go v1.0.7-0.20210312023737-9edc707e7511
 	github.com/natefinch/lumberjack v2.0.0+incompatible
@@ -13,4 +14,5 @@ require (
 	k8s.io/api v0.19.3
 	k8s.io/apimachinery v0.19.3
 	k8s.io/client-go v0.19.3
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -5,7 +5,9 @@ import (
 
 	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
 	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+	tok8s "github.com/nacos-group/nacos-k8s-sync/pkg/to-k8s"
 	tonacos "github.com/nacos-group/nacos-k8s-sync/pkg/to-nacos"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/webhook"
 )
 
 type Options struct {
@@ -13,7 +15,17 @@ type Options struct {
 
 	NacosOptions model.NacosOptions
 
+	SyncOptions model.SyncOptions
+
 	Direction model.Direction
+
+	// MaxRetry is the times to retry a failed sync task.
+	MaxRetry int
+
+	// AnnotationPrefix is the prefix of the keys of the annotations which configure the sync.
+	AnnotationPrefix string
+
+	WebhookOptions webhook.Options
 }
 
 type Server struct {
@@ -22,10 +34,33 @@ type Server struct {
 	toK8sController model.Controller
 
 	kubeClient model.KubeClient
+
+	// webhook is only served when the port of webhook is specified.
+	webhook *webhook.Server
+
+	// remoteClusters are the other k8s clusters synced to nacos, keyed by the cluster id.
+	remoteClusters map[string]*remoteCluster
+}
+
+// remoteCluster watches a remote k8s cluster, whose instances are owned by its own controller.
+type remoteCluster struct {
+	kubeClient model.KubeClient
+
+	toNacosController model.Controller
 }
 
 func NewServer(options Options) (*Server, error) {
-	server := &Server{}
+	server := &Server{
+		remoteClusters: make(map[string]*remoteCluster),
+	}
+
+	model.SetMaxRetry(options.MaxRetry)
+	model.SetAnnotationPrefix(options.AnnotationPrefix)
+
+	if err := options.SyncOptions.Complete(); err != nil {
+		logger.Error("Parse sync options fail.")
+		return nil, err
+	}
 
 	if err := server.initKubeClient(options.KubeOptions); err != nil {
 		return nil, err
@@ -35,6 +70,14 @@ func NewServer(options Options) (*Server, error) {
 		return nil, err
 	}
 
+	if err := server.initRemoteClusters(options); err != nil {
+		return nil, err
+	}
+
+	if err := server.initWebhook(options); err != nil {
+		return nil, err
+	}
+
 	return server, nil
 }
 
@@ -51,20 +94,111 @@ func (s *Server) initKubeClient(options model.KubeOptions) error {
 
 func (s *Server) initController(options Options) error {
 	switch options.Direction {
-	case model.ToNacos:
-		tonacosController, err := tonacos.NewController(options.NacosOptions, options.KubeOptions.WatchedNamespace, s.kubeClient)
+	case model.ToNacos, model.ToK8s, model.Both:
+	default:
+		return fmt.Errorf("not supported type direction %s", options.Direction)
+	}
+
+	if options.Direction == model.ToNacos || options.Direction == model.Both {
+		tonacosController, err := tonacos.NewController(options.NacosOptions, options.SyncOptions,
+			options.KubeOptions, s.kubeClient)
 		if err != nil {
 			logger.Error("Init to nacos controller fail.")
 			return err
 		}
 		s.toNacosController = tonacosController
-	default:
-		return fmt.Errorf("not supported type direction %s", options.Direction)
+	}
+
+	if options.Direction == model.ToK8s || options.Direction == model.Both {
+		tok8sController, err := tok8s.NewController(options.NacosOptions, options.SyncOptions, s.kubeClient)
+		if err != nil {
+			logger.Error("Init to k8s controller fail.")
+			return err
+		}
+		s.toK8sController = tok8sController
 	}
 
 	return nil
 }
 
+func (s *Server) initRemoteClusters(options Options) error {
+	configs, err := model.LoadRemoteClusters(options.KubeOptions, s.kubeClient.Kubernetes())
+	if err != nil {
+		logger.Error("Load remote clusters fail.")
+		return err
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	// The instances of each cluster are distinguished by the cluster id.
+	if s.toNacosController == nil {
+		return fmt.Errorf("remote clusters are only synced to nacos")
+	}
+	if options.SyncOptions.ClusterID == "" {
+		return fmt.Errorf("the id of local cluster is required with remote clusters")
+	}
+	if _, exist := configs[options.SyncOptions.ClusterID]; exist {
+		return fmt.Errorf("remote cluster %s conflicts with local cluster", options.SyncOptions.ClusterID)
+	}
+
+	for clusterID, config := range configs {
+		kubeClient, err := model.NewKubeClientForConfig(config, options.KubeOptions)
+		if err != nil {
+			logger.Errorf("Init kube client of remote cluster %s fail.", clusterID)
+			return err
+		}
+
+		syncOptions := options.SyncOptions
+		syncOptions.ClusterID = clusterID
+		controller, err := tonacos.NewController(options.NacosOptions, syncOptions,
+			options.KubeOptions, kubeClient)
+		if err != nil {
+			logger.Errorf("Init to nacos controller of remote cluster %s fail.", clusterID)
+			return err
+		}
+
+		logger.Infof("Sync remote cluster %s to nacos.", clusterID)
+		s.remoteClusters[clusterID] = &remoteCluster{
+			kubeClient:        kubeClient,
+			toNacosController: controller,
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) initWebhook(options Options) error {
+	if options.WebhookOptions.Port == 0 {
+		return nil
+	}
+
+	server, err := webhook.NewServer(options.WebhookOptions, options.SyncOptions, options.KubeOptions,
+		options.NacosOptions.Namespace, s.kubeClient)
+	if err != nil {
+		logger.Error("Init webhook fail.")
+		return err
+	}
+
+	s.webhook = server
+	return nil
+}
+
+// ReloadKubeOptions applies the filters of services changed at runtime to the controllers syncing
+// services to nacos, including the ones of remote clusters.
+func (s *Server) ReloadKubeOptions(options model.KubeOptions) {
+	controllers := []model.Controller{s.toNacosController}
+	for _, cluster := range s.remoteClusters {
+		controllers = append(controllers, cluster.toNacosController)
+	}
+
+	for _, controller := range controllers {
+		if reloader, ok := controller.(model.KubeOptionsReloader); ok {
+			reloader.ReloadKubeOptions(options)
+		}
+	}
+}
+
 func (s *Server) Run(stop <-chan struct{}) {
 	go s.kubeClient.Run(stop)
 
@@ -75,4 +209,13 @@ func (s *Server) Run(stop <-chan struct{}) {
 	if s.toK8sController != nil {
 		go s.toK8sController.Run(stop)
 	}
+
+	if s.webhook != nil {
+		go s.webhook.Run(stop)
+	}
+
+	for _, cluster := range s.remoteClusters {
+		go cluster.kubeClient.Run(stop)
+		go cluster.toNacosController.Run(stop)
+	}
 }
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/natefinch/lumberjack"
@@ -10,6 +11,9 @@ import (
 
 var (
 	logger *zap.SugaredLogger
+
+	// level is shared by all the loggers configured, so that it can be changed at runtime.
+	level = zap.NewAtomicLevel()
 )
 
 func init() {
@@ -18,14 +22,14 @@ func init() {
 
 // Must be called once at process startup.
 func Configure(options *Options) error {
-	logLevel := options.GetOutputLevel()
+	level.SetLevel(options.GetOutputLevel())
 	encoder := getEncoder(options)
 	writer, err := getWriter(options)
 	if err != nil {
 		return err
 	}
 
-	core := zapcore.NewCore(zapcore.NewConsoleEncoder(encoder), writer, logLevel)
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(encoder), writer, level)
 	raw := zap.New(core, zap.AddCallerSkip(1))
 	logger = raw.Sugar()
 
@@ -132,6 +136,17 @@ func getWriter(options *Options) (zapcore.WriteSyncer, error) {
 	return writer, nil
 }
 
+// SetOutputLevel changes the output level of logger at runtime.
+func SetOutputLevel(outputLevel string) error {
+	l, exist := levelMap[outputLevel]
+	if !exist {
+		return fmt.Errorf("unknown log level %s", outputLevel)
+	}
+
+	level.SetLevel(l)
+	return nil
+}
+
 func GetLogger() *zap.SugaredLogger {
 	return logger
 }
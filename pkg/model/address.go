@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// findServicePort returns the port of service whose target port or port is equal to the given port.
+func findServicePort(service *v1.Service, port uint64) (v1.ServicePort, error) {
+	for _, servicePort := range service.Spec.Ports {
+		if servicePort.TargetPort.IntValue() == int(port) {
+			return servicePort, nil
+		}
+	}
+
+	for _, servicePort := range service.Spec.Ports {
+		if servicePort.Port == int32(port) {
+			return servicePort, nil
+		}
+	}
+
+	return v1.ServicePort{}, fmt.Errorf("port %d not found in service (%s:%s)", port, service.Name, service.Namespace)
+}
+
+// ConvertServiceToAddresses builds the addresses of service according to the address mode
+// which is not pod.
+func ConvertServiceToAddresses(serviceInfo ServiceInfo, service *v1.Service, nodes []*v1.Node) ([]Address, error) {
+	servicePort, err := findServicePort(service, serviceInfo.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []Address
+	newAddress := func(ip string, port int32, nodeName string) {
+		addresses = append(addresses, Address{
+			IP:          ip,
+			Port:        uint64(port),
+			Healthy:     true,
+			Enable:      true,
+			Weight:      serviceInfo.InstanceWeight(),
+			ClusterName: serviceInfo.ClusterName,
+			NodeName:    nodeName,
+		})
+	}
+
+	switch serviceInfo.AddressMode {
+	case AddressModeClusterIP:
+		if service.Spec.ClusterIP == "" || service.Spec.ClusterIP == v1.ClusterIPNone {
+			return nil, fmt.Errorf("service (%s:%s) has no cluster ip", service.Name, service.Namespace)
+		}
+		newAddress(service.Spec.ClusterIP, servicePort.Port, "")
+	case AddressModeNodePort:
+		if servicePort.NodePort == 0 {
+			return nil, fmt.Errorf("service (%s:%s) has no node port", service.Name, service.Namespace)
+		}
+		for _, node := range nodes {
+			if ip := nodeIP(node); ip != "" && IsNodeReady(node) {
+				newAddress(ip, servicePort.NodePort, node.Name)
+			}
+		}
+	case AddressModeLoadBalancer:
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				newAddress(ingress.IP, servicePort.Port, "")
+			} else if ingress.Hostname != "" {
+				newAddress(ingress.Hostname, servicePort.Port, "")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("not supported address mode %s", serviceInfo.AddressMode)
+	}
+
+	return addresses, nil
+}
+
+// nodeIP returns the internal ip of node, and falls back to the external ip.
+func nodeIP(node *v1.Node) string {
+	var externalIP string
+	for _, address := range node.Status.Addresses {
+		switch address.Type {
+		case v1.NodeInternalIP:
+			return address.Address
+		case v1.NodeExternalIP:
+			if externalIP == "" {
+				externalIP = address.Address
+			}
+		}
+	}
+
+	return externalIP
+}
+
+// IsNodeReady returns whether the ready condition of node is true.
+func IsNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -2,38 +2,176 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
 )
 
+// DefaultAnnotationPrefix is the prefix of the keys of annotations by default.
+const DefaultAnnotationPrefix = "nacos.io"
+
+// The names of annotations, whose keys are the annotation prefix joined with them.
 const (
-	// annotationServiceSync is the key of the annotation that determines
+	// annotationServiceSync is the name of the annotation that determines
 	// whether to sync the Service resource or not.
-	annotationServiceSync = "nacos.io/service-sync"
+	annotationServiceSync = "service-sync"
 
 	// annotationServiceName is set to override the name of the service
 	// registered.
-	annotationServiceName = "nacos.io`/service-name"
+	annotationServiceName = "service-name"
 
 	// annotationServiceGroup is set to override the group of the service
 	// registered.
-	annotationServiceGroup = "nacos.io/service-group"
+	annotationServiceGroup = "service-group"
+
+	// annotationNacosNamespace is set to override the nacos namespace which the
+	// service is registered into.
+	annotationNacosNamespace = "nacos-namespace"
 
 	// annotationServicePort specifies the port to use as the service instance
 	// port when registering a service. This can be a named port in the
 	// service or an integer value.
-	annotationServicePort = "nacos.io/service-port"
+	annotationServicePort = "service-port"
+
+	// annotationServiceMeta is the deprecated name of annotationInstanceMeta,
+	// whose meta are registered with the instances rather than the service.
+	annotationServiceMeta = "service-meta"
+
+	// annotationServiceLevelMeta specifies the metadata of nacos service itself
+	// rather than its instances. The format must be json.
+	annotationServiceLevelMeta = "service-level-meta"
+
+	// annotationProtectThreshold specifies the protect threshold of nacos service,
+	// which ranges from 0 to 1.
+	annotationProtectThreshold = "protect-threshold"
+
+	// annotationServiceSelector specifies the label expression of the selector of
+	// nacos service, which selects the instances for consumers.
+	annotationServiceSelector = "service-selector"
+
+	// annotationAllowMassDeregistration is set to allow the instances of the
+	// service to be deregistered massively, bypassing the deregistration guard.
+	annotationAllowMassDeregistration = "allow-mass-deregistration"
+
+	// annotationServiceEphemeral is set to override whether the instances of
+	// the service are registered as ephemeral or persistent instances.
+	annotationServiceEphemeral = "service-ephemeral"
 
-	// annotationServiceMeta specifies the meta of nacos service.
+	// annotationNotReadyPolicy is set to override how to register the not ready
+	// addresses of the service, which can be omit, unhealthy or disabled.
+	annotationNotReadyPolicy = "not-ready-policy"
+
+	// annotationServiceCluster is set to override the nacos cluster of the
+	// instances registered.
+	annotationServiceCluster = "service-cluster"
+
+	// annotationAddressMode specifies which addresses of the service are registered,
+	// which can be pod, clusterIP, nodePort or loadBalancer. Default is pod.
+	annotationAddressMode = "address-mode"
+
+	// annotationInstanceWeight is set on the pod to override the weight of the
+	// instance registered. It is the default weight of the instances of the other
+	// objects, such as ingresses and routes.
+	annotationInstanceWeight = "instance-weight"
+
+	// annotationInstanceMeta specifies the meta of the instances registered, which
+	// is set on the service for all instances, or on the pod for its own instance.
 	// The format must be json.
-	annotationServiceMeta = "nacos.io/service-meta"
+	annotationInstanceMeta = "instance-meta"
+
+	// annotationMetadataPolicy is set to override how the meta of instances are
+	// merged with the ones set outside syncer, which can be overwrite, merge or
+	// preserve-foreign-keys.
+	annotationMetadataPolicy = "metadata-policy"
+
+	// annotationImportService is set on the k8s service to import the instances of
+	// the nacos service with the name as extra endpoints.
+	annotationImportService = "import-service"
+
+	// annotationImportGroup specifies the group of the nacos service imported.
+	annotationImportGroup = "import-group"
+)
+
+// deprecatedAnnotationNames are the deprecated names of annotations, whose keys with both the
+// configured and the default prefix are accepted as the aliases of them.
+var deprecatedAnnotationNames = map[string][]string{
+	annotationInstanceMeta: {annotationServiceMeta},
+}
+
+var (
+	annotationPrefix = DefaultAnnotationPrefix
+
+	// warnedAnnotationKeys records the deprecated keys warned, so that each of them is warned once.
+	warnedAnnotationKeys sync.Map
 )
 
+// SetAnnotationPrefix changes the prefix of the keys of annotations, and the keys with the default
+// prefix are still accepted as the aliases. It must be called before syncing.
+func SetAnnotationPrefix(prefix string) {
+	if prefix = strings.TrimSuffix(prefix, "/"); prefix == "" {
+		prefix = DefaultAnnotationPrefix
+	}
+	annotationPrefix = prefix
+}
+
+// AnnotationKey returns the key of the annotation with the name.
+func AnnotationKey(name string) string {
+	return annotationPrefix + "/" + name
+}
+
+// lookupAnnotation returns the value of the annotation with the name. If the annotation is absent,
+// the aliases of it are looked up with a deprecation warning.
+func lookupAnnotation(annotations map[string]string, name string) (string, bool) {
+	if value, ok := annotations[AnnotationKey(name)]; ok {
+		return value, true
+	}
+
+	var aliases []string
+	if annotationPrefix != DefaultAnnotationPrefix {
+		aliases = append(aliases, DefaultAnnotationPrefix+"/"+name)
+	}
+	for _, deprecated := range deprecatedAnnotationNames[name] {
+		aliases = append(aliases, AnnotationKey(deprecated))
+		if annotationPrefix != DefaultAnnotationPrefix {
+			aliases = append(aliases, DefaultAnnotationPrefix+"/"+deprecated)
+		}
+	}
+	for _, alias := range aliases {
+		if value, ok := annotations[alias]; ok {
+			if _, warned := warnedAnnotationKeys.LoadOrStore(alias, struct{}{}); !warned {
+				logger.Warnf("Annotation %s is deprecated, use %s instead.", alias, AnnotationKey(name))
+			}
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// AllowMassDeregistrationKey returns the key of the annotation which bypasses the deregistration guard.
+func AllowMassDeregistrationKey() string {
+	return AnnotationKey(annotationAllowMassDeregistration)
+}
+
+// annotationOf returns the value of the annotation with the name, and empty if it is absent.
+func annotationOf(annotations map[string]string, name string) string {
+	value, _ := lookupAnnotation(annotations, name)
+	return value
+}
+
 func ShouldServiceSync(svc *v1.Service) bool {
-	raw, ok := svc.Annotations[annotationServiceSync]
+	return ShouldObjectSync(svc)
+}
+
+// ShouldObjectSync determines whether to sync the object, such as service, ingress or route, by its annotations.
+func ShouldObjectSync(obj metav1.Object) bool {
+	raw, ok := lookupAnnotation(obj.GetAnnotations(), annotationServiceSync)
 	if !ok {
 		return false
 	}
@@ -46,35 +184,264 @@ func ShouldServiceSync(svc *v1.Service) bool {
 	return v
 }
 
-func GenerateServiceInfo(svc *v1.Service) (ServiceInfo, error) {
-	serviceName := svc.Annotations[annotationServiceName]
-	if serviceName == "" {
+func GenerateServiceInfo(svc *v1.Service, options SyncOptions) (ServiceInfo, error) {
+	return GenerateObjectInfo(svc, 0, options)
+}
+
+// resolveNotReadyPolicy validates the not ready policy of the instances, and fills the default one.
+// The health of ephemeral instances is reset by their heartbeats in nacos, so they are disabled
+// instead of being unhealthy.
+func resolveNotReadyPolicy(policy NotReadyPolicy, ephemeral bool) (NotReadyPolicy, error) {
+	switch policy {
+	case NotReadyOmit, NotReadyDisabled:
+		return policy, nil
+	case NotReadyUnhealthy:
+		if ephemeral {
+			return NotReadyDisabled, nil
+		}
+		return policy, nil
+	case "":
+		// Persistent instances are owned by syncer, so the not ready addresses
+		// are registered as unhealthy instead of being removed.
+		if !ephemeral {
+			return NotReadyUnhealthy, nil
+		}
+		return NotReadyOmit, nil
+	default:
+		return "", fmt.Errorf("not supported not ready policy %s", policy)
+	}
+}
+
+// GenerateObjectInfo generates the service info from the annotations of object. If the port
+// annotation is absent, the default port is used, and zero default port means that the port
+// annotation is required.
+func GenerateObjectInfo(obj metav1.Object, defaultPort uint64, options SyncOptions) (ServiceInfo, error) {
+	return generateObjectInfo(obj, defaultPort, nil, options)
+}
+
+// generateObjectInfo generates the service info from the annotations of object, and the name, group
+// and metadata generated by the CEL rule, if any, take precedence over the templates and sync rules
+// but are overridden by the annotations.
+func generateObjectInfo(obj metav1.Object, defaultPort uint64, result *CELResult,
+	options SyncOptions) (ServiceInfo, error) {
+	annotations := obj.GetAnnotations()
+	serviceName := annotationOf(annotations, annotationServiceName)
+	if serviceName == "" && result != nil {
+		serviceName = result.ServiceName
+	}
+	nameSpecified := serviceName != ""
+	if !nameSpecified {
 		// fall back to get the name of service resource
 		logger.Info("The service name annotion is empty, so we use the name of service resource.")
-		serviceName = svc.Name
+		serviceName = obj.GetName()
 	}
 
-	port, err := strconv.ParseUint(svc.Annotations[annotationServicePort], 0, 0)
-	if err != nil {
-		return ServiceInfo{}, err
+	var port uint64
+	var err error
+	if raw := annotationOf(annotations, annotationServicePort); raw != "" || defaultPort == 0 {
+		if port, err = strconv.ParseUint(raw, 0, 0); err != nil {
+			return ServiceInfo{}, err
+		}
+	} else {
+		port = defaultPort
 	}
 
 	var meta map[string]string
-	rawMeta := svc.Annotations[annotationServiceMeta]
+	rawMeta := annotationOf(annotations, annotationInstanceMeta)
 	if rawMeta != "" {
-		if err := json.Unmarshal([]byte(svc.Annotations[annotationServiceMeta]), &meta); err != nil {
+		if err := json.Unmarshal([]byte(rawMeta), &meta); err != nil {
+			return ServiceInfo{}, err
+		}
+	}
+	if result != nil && len(result.Metadata) > 0 {
+		meta = MergeMetadata(result.Metadata, meta)
+	}
+
+	ephemeral := options.Ephemeral
+	if raw, ok := lookupAnnotation(annotations, annotationServiceEphemeral); ok {
+		if ephemeral, err = strconv.ParseBool(raw); err != nil {
+			return ServiceInfo{}, err
+		}
+	}
+
+	notReadyPolicy := options.NotReadyPolicy
+	if raw, ok := lookupAnnotation(annotations, annotationNotReadyPolicy); ok {
+		notReadyPolicy = NotReadyPolicy(raw)
+	}
+	if notReadyPolicy, err = resolveNotReadyPolicy(notReadyPolicy, ephemeral); err != nil {
+		return ServiceInfo{}, err
+	}
+
+	allowMassDeregistration := false
+	if raw, ok := lookupAnnotation(annotations, annotationAllowMassDeregistration); ok {
+		if allowMassDeregistration, err = strconv.ParseBool(raw); err != nil {
+			return ServiceInfo{}, err
+		}
+	}
+
+	var weight float64
+	if raw, ok := lookupAnnotation(annotations, annotationInstanceWeight); ok {
+		if weight, err = strconv.ParseFloat(raw, 64); err != nil {
 			return ServiceInfo{}, err
 		}
+		if weight < 0 {
+			return ServiceInfo{}, fmt.Errorf("invalid instance weight %s", raw)
+		}
+	}
+
+	metadataPolicy := options.MetadataPolicy
+	if raw, ok := lookupAnnotation(annotations, annotationMetadataPolicy); ok {
+		metadataPolicy = MetadataPolicy(raw)
+	}
+	switch metadataPolicy {
+	case MetadataOverwrite, MetadataMerge, MetadataPreserveForeignKeys:
+	case "":
+		metadataPolicy = MetadataOverwrite
+	default:
+		return ServiceInfo{}, fmt.Errorf("not supported metadata policy %s", metadataPolicy)
+	}
+
+	settings, err := generateServiceSettings(annotations)
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+
+	addressMode := AddressModePod
+	if raw, ok := lookupAnnotation(annotations, annotationAddressMode); ok {
+		addressMode = AddressMode(raw)
+	}
+	switch addressMode {
+	case AddressModePod, AddressModeClusterIP, AddressModeNodePort, AddressModeLoadBalancer:
+	default:
+		return ServiceInfo{}, fmt.Errorf("not supported address mode %s", addressMode)
+	}
+
+	group := annotationOf(annotations, annotationServiceGroup)
+	if group == "" && result != nil {
+		group = result.Group
 	}
 
 	// Now we only trust the annotations.
 	// TODO Extract value from the spec of service resource for extended features
-	return ServiceInfo{
+	serviceInfo := ServiceInfo{
 		ServiceKey: ServiceKey{
 			ServiceName: serviceName,
-			Group:       svc.Annotations[annotationServiceGroup],
+			Group:       group,
+			Namespace:   annotationOf(annotations, annotationNacosNamespace),
 		},
-		Port:     port,
-		Metadata: meta,
-	}, nil
+		Port:           port,
+		Metadata:       meta,
+		Ephemeral:      ephemeral,
+		NotReadyPolicy: notReadyPolicy,
+		Weight:         weight,
+		ClusterName:    annotationOf(annotations, annotationServiceCluster),
+		AddressMode:    addressMode,
+		MetadataPolicy: metadataPolicy,
+		Settings:       settings,
+
+		AllowMassDeregistration: allowMassDeregistration,
+	}
+	if err := applyServiceKeyTemplates(obj, &serviceInfo, nameSpecified, serviceInfo.Group != "", options); err != nil {
+		return ServiceInfo{}, err
+	}
+	options.Rules.apply(obj, &serviceInfo)
+
+	return serviceInfo, nil
+}
+
+// generateServiceSettings extracts the settings of nacos service from the annotations, and returns
+// nil if none of them is annotated.
+func generateServiceSettings(annotations map[string]string) (*ServiceSettings, error) {
+	var settings ServiceSettings
+	specified := false
+
+	if rawMeta, ok := lookupAnnotation(annotations, annotationServiceLevelMeta); ok {
+		if err := json.Unmarshal([]byte(rawMeta), &settings.Metadata); err != nil {
+			return nil, err
+		}
+		if settings.Metadata == nil {
+			settings.Metadata = make(map[string]string)
+		}
+		specified = true
+	}
+
+	if raw, ok := lookupAnnotation(annotations, annotationProtectThreshold); ok {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		if threshold < 0 || threshold > 1 {
+			return nil, fmt.Errorf("invalid protect threshold %s", raw)
+		}
+		settings.ProtectThreshold = &threshold
+		specified = true
+	}
+
+	if raw, ok := lookupAnnotation(annotations, annotationServiceSelector); ok {
+		settings.Selector = &raw
+		specified = true
+	}
+
+	if !specified {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+// GenerateInstanceInfo extracts the weight and metadata of instance from the pod.
+// The default weight is used if the pod has no weight annotation. The metadata consists
+// of the allowed labels and the instance meta annotation, and the latter takes precedence.
+func GenerateInstanceInfo(pod *v1.Pod, defaultWeight float64, options SyncOptions) (InstanceInfo, error) {
+	info := InstanceInfo{
+		Weight: defaultWeight,
+	}
+
+	if raw, ok := lookupAnnotation(pod.Annotations, annotationInstanceWeight); ok {
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return InstanceInfo{}, err
+		}
+		if weight < 0 {
+			return InstanceInfo{}, fmt.Errorf("invalid instance weight %s", raw)
+		}
+		info.Weight = weight
+	}
+
+	for _, key := range options.PodLabelsAsMetadata {
+		if value, ok := pod.Labels[key]; ok {
+			if info.Metadata == nil {
+				info.Metadata = make(map[string]string)
+			}
+			info.Metadata[key] = value
+		}
+	}
+
+	if rawMeta := annotationOf(pod.Annotations, annotationInstanceMeta); rawMeta != "" {
+		var meta map[string]string
+		if err := json.Unmarshal([]byte(rawMeta), &meta); err != nil {
+			return InstanceInfo{}, err
+		}
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string, len(meta))
+		}
+		for key, value := range meta {
+			info.Metadata[key] = value
+		}
+	}
+
+	return info, nil
+}
+
+// ImportServiceKey returns the key of nacos service which is imported into the k8s service.
+// It returns false if the k8s service imports nothing.
+func ImportServiceKey(svc *v1.Service) (ServiceKey, bool) {
+	serviceName := annotationOf(svc.Annotations, annotationImportService)
+	if serviceName == "" {
+		return ServiceKey{}, false
+	}
+
+	return ServiceKey{
+		ServiceName: serviceName,
+		Group:       annotationOf(svc.Annotations, annotationImportGroup),
+	}, true
 }
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"strconv"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLookupAnnotation(t *testing.T) {
+	defer SetAnnotationPrefix(DefaultAnnotationPrefix)
+
+	cases := []struct {
+		name        string
+		prefix      string
+		annotations map[string]string
+		lookup      string
+		value       string
+		found       bool
+	}{
+		{
+			name:        "configured prefix",
+			prefix:      "example.com",
+			annotations: map[string]string{"example.com/service-name": "a", "nacos.io/service-name": "b"},
+			lookup:      annotationServiceName,
+			value:       "a",
+			found:       true,
+		},
+		{
+			name:        "default prefix",
+			prefix:      "example.com",
+			annotations: map[string]string{"nacos.io/service-name": "b"},
+			lookup:      annotationServiceName,
+			value:       "b",
+			found:       true,
+		},
+		{
+			name:   "name before deprecated name",
+			prefix: DefaultAnnotationPrefix,
+			annotations: map[string]string{
+				"nacos.io/instance-meta": `{"a":"1"}`,
+				"nacos.io/service-meta":  `{"b":"2"}`,
+			},
+			lookup: annotationInstanceMeta,
+			value:  `{"a":"1"}`,
+			found:  true,
+		},
+		{
+			name:   "default prefix before deprecated name",
+			prefix: "example.com",
+			annotations: map[string]string{
+				"nacos.io/instance-meta":   `{"a":"1"}`,
+				"example.com/service-meta": `{"b":"2"}`,
+			},
+			lookup: annotationInstanceMeta,
+			value:  `{"a":"1"}`,
+			found:  true,
+		},
+		{
+			name:   "deprecated name with configured prefix first",
+			prefix: "example.com",
+			annotations: map[string]string{
+				"example.com/service-meta": `{"b":"2"}`,
+				"nacos.io/service-meta":    `{"c":"3"}`,
+			},
+			lookup: annotationInstanceMeta,
+			value:  `{"b":"2"}`,
+			found:  true,
+		},
+		{
+			name:        "deprecated name with default prefix",
+			prefix:      "example.com",
+			annotations: map[string]string{"nacos.io/service-meta": `{"c":"3"}`},
+			lookup:      annotationInstanceMeta,
+			value:       `{"c":"3"}`,
+			found:       true,
+		},
+		{
+			name:        "absent",
+			prefix:      "example.com",
+			annotations: map[string]string{"other.io/service-name": "a"},
+			lookup:      annotationServiceName,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			SetAnnotationPrefix(c.prefix)
+			value, found := lookupAnnotation(c.annotations, c.lookup)
+			if value != c.value || found != c.found {
+				t.Errorf("got (%q, %v), want (%q, %v)", value, found, c.value, c.found)
+			}
+		})
+	}
+}
+
+func TestNotReadyPolicy(t *testing.T) {
+	cases := []struct {
+		name      string
+		ephemeral bool
+		policy    NotReadyPolicy
+		want      NotReadyPolicy
+		wantErr   bool
+	}{
+		{name: "ephemeral default", ephemeral: true, want: NotReadyOmit},
+		{name: "persistent default", want: NotReadyUnhealthy},
+		{name: "ephemeral unhealthy", ephemeral: true, policy: NotReadyUnhealthy, want: NotReadyDisabled},
+		{name: "persistent unhealthy", policy: NotReadyUnhealthy, want: NotReadyUnhealthy},
+		{name: "ephemeral disabled", ephemeral: true, policy: NotReadyDisabled, want: NotReadyDisabled},
+		{name: "persistent omit", policy: NotReadyOmit, want: NotReadyOmit},
+		{name: "invalid", ephemeral: true, policy: "drop", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"nacos.io/service-port":      "8080",
+						"nacos.io/service-ephemeral": strconv.FormatBool(c.ephemeral),
+					},
+				},
+				Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{Port: 8080}}},
+			}
+			if c.policy != "" {
+				service.Annotations["nacos.io/not-ready-policy"] = string(c.policy)
+			}
+
+			info, err := GenerateServiceInfo(service, SyncOptions{})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("annotation: err %v, want error %v", err, c.wantErr)
+			}
+			if err == nil && info.NotReadyPolicy != c.want {
+				t.Errorf("annotation: got %s, want %s", info.NotReadyPolicy, c.want)
+			}
+
+			serviceSync := &NacosServiceSync{Spec: NacosServiceSyncSpec{
+				ServiceRef:     ServiceReference{Name: "foo"},
+				Ephemeral:      &c.ephemeral,
+				NotReadyPolicy: c.policy,
+			}}
+			info, err = GenerateServiceSyncInfo(service, serviceSync, SyncOptions{})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("NacosServiceSync: err %v, want error %v", err, c.wantErr)
+			}
+			if err == nil && info.NotReadyPolicy != c.want {
+				t.Errorf("NacosServiceSync: got %s, want %s", info.NotReadyPolicy, c.want)
+			}
+		})
+	}
+}
@@ -4,3 +4,8 @@ type Controller interface {
 	Run(<-chan struct{})
 	HasSynced() bool
 }
+
+// KubeOptionsReloader is the controller which applies the filters of services changed at runtime.
+type KubeOptionsReloader interface {
+	ReloadKubeOptions(KubeOptions)
+}
@@ -0,0 +1,242 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+)
+
+// CELRule decides whether and how to sync the services selected by it with CEL expressions, which
+// are evaluated against the variables service, namespaceObject and endpoints.
+type CELRule struct {
+	// Name identifies the rule in the errors reported.
+	Name string `json:"name"`
+
+	// Match selects the services which the rule applies to, such as
+	// has(namespaceObject.metadata.labels.team) && namespaceObject.metadata.labels.team == "x".
+	// The match which fails to evaluate does not select the service.
+	Match string `json:"match"`
+
+	// Sync determines whether the services selected are synced. Empty means true.
+	Sync string `json:"sync,omitempty"`
+
+	// ServiceName and Group generate the name and group of nacos service, which are overridden
+	// by the annotations.
+	ServiceName string `json:"serviceName,omitempty"`
+	Group       string `json:"group,omitempty"`
+
+	// Port generates the port registered, which is overridden by the port annotation.
+	Port string `json:"port,omitempty"`
+
+	// Metadata generates the values of the metadata keys, which are overridden by the meta annotation.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// CELResult is the outcome of the CEL rule which selects the service.
+type CELResult struct {
+	Rule string
+
+	Sync bool
+
+	ServiceName string
+	Group       string
+	Port        uint64
+	Metadata    map[string]string
+}
+
+type celProgram struct {
+	rule CELRule
+
+	match       cel.Program
+	sync        cel.Program
+	serviceName cel.Program
+	group       cel.Program
+	port        cel.Program
+	metadata    map[string]cel.Program
+}
+
+var celEnv *cel.Env
+
+func init() {
+	var err error
+	celEnv, err = cel.NewEnv(
+		cel.Variable("service", cel.DynType),
+		cel.Variable("namespaceObject", cel.DynType),
+		cel.Variable("endpoints", cel.DynType),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func compileCELRules(rules []CELRule) ([]celProgram, error) {
+	programs := make([]celProgram, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("match of cel rule %s is required", rule.Name)
+		}
+
+		p := celProgram{rule: rule}
+		var err error
+		for _, item := range []struct {
+			expr    string
+			program *cel.Program
+		}{
+			{rule.Match, &p.match},
+			{rule.Sync, &p.sync},
+			{rule.ServiceName, &p.serviceName},
+			{rule.Group, &p.group},
+			{rule.Port, &p.port},
+		} {
+			if *item.program, err = compileCEL(item.expr); err != nil {
+				return nil, fmt.Errorf("compile cel rule %s fail, err %v", rule.Name, err)
+			}
+		}
+
+		p.metadata = make(map[string]cel.Program, len(rule.Metadata))
+		for key, expr := range rule.Metadata {
+			if p.metadata[key], err = compileCEL(expr); err != nil {
+				return nil, fmt.Errorf("compile metadata %s of cel rule %s fail, err %v", key, rule.Name, err)
+			}
+		}
+		programs = append(programs, p)
+	}
+
+	return programs, nil
+}
+
+func compileCEL(expr string) (cel.Program, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	ast, issues := celEnv.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	return celEnv.Program(ast)
+}
+
+// HasCELRules returns whether there are CEL rules to evaluate.
+func (r *SyncRules) HasCELRules() bool {
+	return r != nil && len(r.celPrograms) > 0
+}
+
+// EvaluateCEL evaluates the CEL rules against the service in order, and returns the result of the first
+// rule which selects the service. It returns nil if no rule selects the service.
+func (r *SyncRules) EvaluateCEL(service *v1.Service, namespace *v1.Namespace, endpoints *v1.Endpoints) (*CELResult, error) {
+	if !r.HasCELRules() {
+		return nil, nil
+	}
+
+	vars := map[string]interface{}{
+		"service":         map[string]interface{}{},
+		"namespaceObject": map[string]interface{}{},
+		"endpoints":       map[string]interface{}{},
+	}
+	var err error
+	if service != nil {
+		if vars["service"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(service); err != nil {
+			return nil, err
+		}
+	}
+	if namespace != nil {
+		if vars["namespaceObject"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(namespace); err != nil {
+			return nil, err
+		}
+	}
+	if endpoints != nil {
+		if vars["endpoints"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(endpoints); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range r.celPrograms {
+		// The match which fails to evaluate, such as selecting a missing label, does not select the service.
+		matched, err := evalCEL(p.match, vars)
+		if err != nil {
+			logger.Debugf("Evaluate match of cel rule %s fail, err %v.", p.rule.Name, err)
+			continue
+		}
+		if matched != types.True {
+			continue
+		}
+
+		result := &CELResult{Rule: p.rule.Name, Sync: true}
+		if p.sync != nil {
+			sync, err := evalCEL(p.sync, vars)
+			if err != nil {
+				return nil, fmt.Errorf("evaluate sync of cel rule %s fail, err %v", p.rule.Name, err)
+			}
+			result.Sync = sync == types.True
+		}
+
+		if result.ServiceName, err = evalCELString(p.serviceName, vars); err != nil {
+			return nil, fmt.Errorf("evaluate service name of cel rule %s fail, err %v", p.rule.Name, err)
+		}
+		if result.Group, err = evalCELString(p.group, vars); err != nil {
+			return nil, fmt.Errorf("evaluate group of cel rule %s fail, err %v", p.rule.Name, err)
+		}
+
+		if p.port != nil {
+			port, err := evalCEL(p.port, vars)
+			if err != nil {
+				return nil, fmt.Errorf("evaluate port of cel rule %s fail, err %v", p.rule.Name, err)
+			}
+			value, ok := port.Value().(int64)
+			if !ok || value <= 0 {
+				return nil, fmt.Errorf("port of cel rule %s is not a positive int but %v", p.rule.Name, port.Value())
+			}
+			result.Port = uint64(value)
+		}
+
+		for key, program := range p.metadata {
+			value, err := evalCELString(program, vars)
+			if err != nil {
+				return nil, fmt.Errorf("evaluate metadata %s of cel rule %s fail, err %v", key, p.rule.Name, err)
+			}
+			if result.Metadata == nil {
+				result.Metadata = make(map[string]string, len(p.metadata))
+			}
+			result.Metadata[key] = value
+		}
+
+		return result, nil
+	}
+
+	return nil, nil
+}
+
+func evalCEL(program cel.Program, vars map[string]interface{}) (ref.Val, error) {
+	out, _, err := program.Eval(vars)
+	return out, err
+}
+
+func evalCELString(program cel.Program, vars map[string]interface{}) (string, error) {
+	if program == nil {
+		return "", nil
+	}
+
+	out, err := evalCEL(program, vars)
+	if err != nil {
+		return "", err
+	}
+	if str, ok := out.Value().(string); ok {
+		return str, nil
+	}
+
+	return fmt.Sprint(out.Value()), nil
+}
+
+// GenerateCELServiceInfo generates the service info of the service selected by CEL rule. The annotations
+// take precedence over the result of rule, which takes precedence over the templates and sync rules.
+func GenerateCELServiceInfo(svc *v1.Service, result *CELResult, options SyncOptions) (ServiceInfo, error) {
+	return generateObjectInfo(svc, result.Port, result, options)
+}
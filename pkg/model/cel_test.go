@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGenerateCELServiceInfoPrecedence(t *testing.T) {
+	rules, err := ParseSyncRules(`
+serviceNameTemplate: "{{.Namespace}}-{{.Name}}"
+groupTemplate: "{{.Namespace}}-group"
+namespaceMapping:
+  default: dev
+defaultMetadata:
+  team: rules
+  zone: rules
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := SyncOptions{Rules: rules}
+
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		result      CELResult
+		want        ServiceKey
+		metadata    map[string]string
+	}{
+		{
+			name:     "templates without cel result",
+			result:   CELResult{Port: 8080},
+			want:     ServiceKey{Namespace: "dev", ServiceName: "default-foo", Group: "default-group"},
+			metadata: map[string]string{"team": "rules", "zone": "rules"},
+		},
+		{
+			name:     "cel result before templates and rules",
+			result:   CELResult{Port: 8080, ServiceName: "cel", Group: "cel-group", Metadata: map[string]string{"team": "cel"}},
+			want:     ServiceKey{Namespace: "dev", ServiceName: "cel", Group: "cel-group"},
+			metadata: map[string]string{"team": "cel", "zone": "rules"},
+		},
+		{
+			name: "annotations before cel result",
+			annotations: map[string]string{
+				"nacos.io/service-name":  "annotated",
+				"nacos.io/service-group": "annotated-group",
+				"nacos.io/instance-meta": `{"team":"annotated"}`,
+			},
+			result:   CELResult{Port: 8080, ServiceName: "cel", Group: "cel-group", Metadata: map[string]string{"team": "cel"}},
+			want:     ServiceKey{Namespace: "dev", ServiceName: "annotated", Group: "annotated-group"},
+			metadata: map[string]string{"team": "annotated", "zone": "rules"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Annotations: c.annotations}}
+			result := c.result
+			info, err := GenerateCELServiceInfo(svc, &result, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ServiceKey != c.want {
+				t.Errorf("got key %+v, want %+v", info.ServiceKey, c.want)
+			}
+			if len(info.Metadata) != len(c.metadata) {
+				t.Fatalf("got metadata %v, want %v", info.Metadata, c.metadata)
+			}
+			for key, value := range c.metadata {
+				if info.Metadata[key] != value {
+					t.Errorf("metadata %s: got %q, want %q", key, info.Metadata[key], value)
+				}
+			}
+		})
+	}
+}
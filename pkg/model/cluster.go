@@ -0,0 +1,83 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RemoteClusterLabel returns the label which marks the secrets holding the kubeconfigs of remote
+// clusters, such as nacos.io/remote-cluster with the default annotation prefix.
+func RemoteClusterLabel() string {
+	return AnnotationKey("remote-cluster")
+}
+
+// LoadRemoteClusters returns the configs of remote clusters keyed by the cluster id, which are
+// loaded from the kubeconfig files and the secrets in the local cluster. They are loaded once, so
+// the changes of them take effect after restart.
+func LoadRemoteClusters(options KubeOptions, client kubernetes.Interface) (map[string]*rest.Config, error) {
+	configs := make(map[string]*rest.Config)
+	add := func(clusterID string, config *rest.Config) error {
+		if clusterID == "" {
+			return fmt.Errorf("the id of remote cluster is required")
+		}
+		if _, exist := configs[clusterID]; exist {
+			return fmt.Errorf("duplicated remote cluster %s", clusterID)
+		}
+		configs[clusterID] = config
+		return nil
+	}
+
+	for _, raw := range options.RemoteClusters {
+		parts := strings.SplitN(raw, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid remote cluster %s", raw)
+		}
+
+		path, contextName := parts[1], ""
+		if i := strings.LastIndex(path, "#"); i >= 0 {
+			path, contextName = path[:i], path[i+1:]
+		}
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: path},
+			&clientcmd.ConfigOverrides{CurrentContext: contextName}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("load kubeconfig of remote cluster %s fail, err %v", parts[0], err)
+		}
+		if err := add(parts[0], config); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.RemoteSecretNamespace == "" {
+		return configs, nil
+	}
+
+	secrets, err := client.CoreV1().Secrets(options.RemoteSecretNamespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{RemoteClusterLabel(): "true"}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range secrets.Items {
+		for clusterID, kubeConfig := range secret.Data {
+			config, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+			if err != nil {
+				return nil, fmt.Errorf("load kubeconfig of remote cluster %s from secret %s fail, err %v",
+					clusterID, secret.Name, err)
+			}
+			if err := add(clusterID, config); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return configs, nil
+}
@@ -0,0 +1,15 @@
+package model
+
+import "testing"
+
+func TestRemoteClusterLabel(t *testing.T) {
+	defer SetAnnotationPrefix(DefaultAnnotationPrefix)
+
+	if got := RemoteClusterLabel(); got != "nacos.io/remote-cluster" {
+		t.Errorf("got %s with default prefix", got)
+	}
+	SetAnnotationPrefix("example.com/")
+	if got := RemoteClusterLabel(); got != "example.com/remote-cluster" {
+		t.Errorf("got %s with configured prefix", got)
+	}
+}
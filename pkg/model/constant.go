@@ -7,6 +7,18 @@ type Event int
 
 type Direction string
 
+// NotReadyPolicy determines how to register the not ready addresses of endpoints.
+type NotReadyPolicy string
+
+// DrainMode determines how to drain the instances of terminating pods.
+type DrainMode string
+
+// AddressMode determines which addresses of service are registered.
+type AddressMode string
+
+// MetadataPolicy determines how the metadata of instances are merged with the ones set outside syncer.
+type MetadataPolicy string
+
 const (
 	// EventAdd is sent when an object is added
 	EventAdd Event = iota
@@ -25,11 +37,68 @@ const (
 
 	DefaultNacosEndpointWeight = 100
 
-	MaxRetry = 3
+	// DefaultMaxRetry is the default times to retry a failed task.
+	DefaultMaxRetry = 3
+
+	// DefaultMCSResyncInterval is the interval to discover the nacos services exported by other clusters.
+	DefaultMCSResyncInterval = 30 * time.Second
+
+	// DefaultServiceSettingsResyncInterval is the interval to revert the settings of nacos services
+	// changed outside syncer.
+	DefaultServiceSettingsResyncInterval = 1 * time.Minute
+
+	// DefaultServiceSettingsReconcileTimeout bounds the time which each round of reverting the settings
+	// of nacos services blocks the other tasks.
+	DefaultServiceSettingsReconcileTimeout = 5 * time.Second
+
+	// DefaultDeregistrationWindow is the window in which the deregistrations of all services are
+	// limited by the cluster threshold of guard.
+	DefaultDeregistrationWindow = 1 * time.Minute
+
+	listServicesPageSize = 100
 
 	ToNacos Direction = "to-nacos"
 
 	ToK8s Direction = "to-k8s"
 
 	Both Direction = "both"
+
+	// NotReadyOmit omits the not ready addresses.
+	NotReadyOmit NotReadyPolicy = "omit"
+
+	// NotReadyUnhealthy registers the not ready addresses as unhealthy instances. The health of
+	// ephemeral instances is refreshed by heartbeat in nacos, so they are disabled instead.
+	NotReadyUnhealthy NotReadyPolicy = "unhealthy"
+
+	// NotReadyDisabled registers the not ready addresses as disabled instances.
+	NotReadyDisabled NotReadyPolicy = "disabled"
+
+	// MetadataOverwrite registers the instances with the metadata of syncer only, and the metadata
+	// set outside syncer are removed.
+	MetadataOverwrite MetadataPolicy = "overwrite"
+
+	// MetadataMerge merges the metadata of syncer into the existing ones, and nothing is removed.
+	MetadataMerge MetadataPolicy = "merge"
+
+	// MetadataPreserveForeignKeys keeps the metadata set outside syncer, while the ones owned by
+	// syncer are added, updated and removed along with the k8s resources.
+	MetadataPreserveForeignKeys MetadataPolicy = "preserve-foreign-keys"
+
+	// DrainDisable drains the instances by disabling them.
+	DrainDisable DrainMode = "disable"
+
+	// DrainWeight drains the instances by setting their weight to zero.
+	DrainWeight DrainMode = "weight"
+
+	// AddressModePod registers the pod ips of endpoints.
+	AddressModePod AddressMode = "pod"
+
+	// AddressModeClusterIP registers the cluster ip of service.
+	AddressModeClusterIP AddressMode = "clusterIP"
+
+	// AddressModeNodePort registers the ip of each node with the node port of service.
+	AddressModeNodePort AddressMode = "nodePort"
+
+	// AddressModeLoadBalancer registers the ingress ips and hostnames of load balancer.
+	AddressModeLoadBalancer AddressMode = "loadBalancer"
 )
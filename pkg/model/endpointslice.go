@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+)
+
+// EndpointSliceManagedBy is the value of managed-by label of the endpoint slices maintained by syncer.
+const EndpointSliceManagedBy = "nacos-k8s-sync"
+
+type sliceKey struct {
+	port        uint64
+	addressType discoveryv1beta1.AddressType
+}
+
+// ConvertToEndpointSlices builds the endpoint slices of the k8s service from the addresses of nacos.
+// The addresses are grouped by port and address type, and the port of slice is named after the port
+// of service whose target port matches it, so that kube-proxy routes the traffic of service to them.
+func ConvertToEndpointSlices(svc *v1.Service, addresses []Address) []*discoveryv1beta1.EndpointSlice {
+	var slices []*discoveryv1beta1.EndpointSlice
+	for key, endpoints := range groupEndpoints(addresses) {
+		servicePort, ok := importedServicePort(svc, key.port)
+		if !ok {
+			logger.Warnf("Port %d not found in service (%s:%s), skip the imported instances.",
+				key.port, svc.Name, svc.Namespace)
+			continue
+		}
+
+		name := fmt.Sprintf("%s-nacos-%d", svc.Name, key.port)
+		if key.addressType == discoveryv1beta1.AddressTypeIPv6 {
+			name += "-ipv6"
+		}
+		name = sanitizeName(name)
+		portName := servicePort.Name
+		port := int32(key.port)
+		protocol := servicePort.Protocol
+		slices = append(slices, &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: svc.Namespace,
+				Labels: map[string]string{
+					discoveryv1beta1.LabelServiceName: svc.Name,
+					discoveryv1beta1.LabelManagedBy:   EndpointSliceManagedBy,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(svc, v1.SchemeGroupVersion.WithKind("Service")),
+				},
+			},
+			AddressType: key.addressType,
+			Endpoints:   endpoints,
+			Ports: []discoveryv1beta1.EndpointPort{{
+				Name:     &portName,
+				Port:     &port,
+				Protocol: &protocol,
+			}},
+		})
+	}
+
+	return slices
+}
+
+// groupEndpoints converts the addresses into endpoints grouped by port and address type.
+func groupEndpoints(addresses []Address) map[sliceKey][]discoveryv1beta1.Endpoint {
+	grouped := make(map[sliceKey][]discoveryv1beta1.Endpoint)
+	for _, address := range addresses {
+		ip := net.ParseIP(address.IP)
+		if ip == nil {
+			// The hostname can not be used as endpoint of service.
+			continue
+		}
+
+		addressType := discoveryv1beta1.AddressTypeIPv4
+		if ip.To4() == nil {
+			addressType = discoveryv1beta1.AddressTypeIPv6
+		}
+
+		ready := address.Healthy && address.Enable && address.Weight > 0
+		key := sliceKey{port: address.Port, addressType: addressType}
+		grouped[key] = append(grouped[key], discoveryv1beta1.Endpoint{
+			Addresses: []string{address.IP},
+			Conditions: discoveryv1beta1.EndpointConditions{
+				Ready: &ready,
+			},
+		})
+	}
+
+	for _, endpoints := range grouped {
+		sort.Slice(endpoints, func(i, j int) bool {
+			return endpoints[i].Addresses[0] < endpoints[j].Addresses[0]
+		})
+	}
+
+	return grouped
+}
+
+// importedServicePort returns the port of service whose target port is equal to the given port.
+// If the service has only one port, it is returned.
+func importedServicePort(svc *v1.Service, port uint64) (v1.ServicePort, bool) {
+	for _, servicePort := range svc.Spec.Ports {
+		if servicePort.TargetPort.IntValue() == int(port) {
+			return servicePort, true
+		}
+	}
+
+	if len(svc.Spec.Ports) == 1 {
+		return svc.Spec.Ports[0], true
+	}
+
+	return v1.ServicePort{}, false
+}
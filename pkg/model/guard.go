@@ -0,0 +1,219 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GuardOptions configures the guard which protects the instances in nacos from being deregistered
+// massively, such as by an empty relist of endpoints.
+type GuardOptions struct {
+	// ServiceThreshold is the most instances of a service deregistered at once, which is an absolute
+	// number or a percentage of its registered instances. Empty means no limit.
+	ServiceThreshold string
+
+	// ClusterThreshold is the most instances of all services deregistered within the window, which is
+	// an absolute number or a percentage of all registered instances. Empty means no limit.
+	ClusterThreshold string
+
+	// Delay is how long the refused deregistration is delayed before being allowed. Zero means that it
+	// is refused until being allowed manually.
+	Delay time.Duration
+}
+
+// Threshold is the limit of deregistrations, which is an absolute number or a percentage.
+type Threshold struct {
+	value float64
+
+	percent bool
+
+	enabled bool
+}
+
+// ParseThreshold parses the threshold formatted as number or percentage such as 50%, and the empty
+// one is disabled.
+func ParseThreshold(raw string) (Threshold, error) {
+	if raw == "" {
+		return Threshold{}, nil
+	}
+
+	percent := strings.HasSuffix(raw, "%")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+	if err != nil || value < 0 || (percent && value > 100) {
+		return Threshold{}, fmt.Errorf("invalid threshold %s", raw)
+	}
+
+	return Threshold{value: value, percent: percent, enabled: true}, nil
+}
+
+// Exceeded returns whether the count of deregistrations exceeds the threshold of the total instances.
+func (t Threshold) Exceeded(count, total int) bool {
+	if !t.enabled {
+		return false
+	}
+	if t.percent {
+		return float64(count) > t.value*float64(total)/100
+	}
+	return float64(count) > t.value
+}
+
+// DeregistrationRefusedError is returned when the deregistration of instances is refused by guard.
+type DeregistrationRefusedError struct {
+	Reason string
+
+	// RetryAfter is how long the deregistration is delayed, and zero means that it is refused until
+	// being allowed manually.
+	RetryAfter time.Duration
+}
+
+func (e *DeregistrationRefusedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("deregistration is delayed for %s, %s", e.RetryAfter, e.Reason)
+	}
+	return fmt.Sprintf("deregistration is refused, %s", e.Reason)
+}
+
+// deregistration is the instances deregistered at once.
+type deregistration struct {
+	at time.Time
+
+	count int
+}
+
+// DeregistrationGuard refuses or delays the deregistrations which exceed the thresholds. It is not
+// safe for concurrent use, as the nacos client owning it.
+type DeregistrationGuard struct {
+	serviceThreshold Threshold
+
+	clusterThreshold Threshold
+
+	delay time.Duration
+
+	// refused records when the deregistration of service was refused first.
+	refused map[ServiceKey]time.Time
+
+	// recent are the deregistrations within the window, which are limited by the cluster threshold.
+	recent []deregistration
+}
+
+// NewDeregistrationGuard creates the guard, and returns nil if no threshold is configured.
+func NewDeregistrationGuard(options GuardOptions) (*DeregistrationGuard, error) {
+	serviceThreshold, err := ParseThreshold(options.ServiceThreshold)
+	if err != nil {
+		return nil, err
+	}
+	clusterThreshold, err := ParseThreshold(options.ClusterThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if !serviceThreshold.enabled && !clusterThreshold.enabled {
+		return nil, nil
+	}
+
+	return &DeregistrationGuard{
+		serviceThreshold: serviceThreshold,
+		clusterThreshold: clusterThreshold,
+		delay:            options.Delay,
+		refused:          make(map[ServiceKey]time.Time),
+	}, nil
+}
+
+// Check returns an error if deregistering the count of instances from the service should be refused.
+// The total is the registered instances of the service, and the clusterTotal is the ones of all services.
+func (g *DeregistrationGuard) Check(serviceKey ServiceKey, count, total, clusterTotal int) error {
+	if g == nil || count == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var reason string
+	if g.serviceThreshold.Exceeded(count, total) {
+		reason = fmt.Sprintf("%d of %d instances of service (%s@@%s) exceed the threshold",
+			count, total, serviceKey.ServiceName, serviceKey.Group)
+	} else {
+		reason = g.clusterReason(now, count, clusterTotal)
+	}
+	return g.refuse(serviceKey, reason, now)
+}
+
+// CheckUnregistration returns an error if unregistering the count of instances with the whole service
+// should be refused. The service is removed on purpose, so only the cluster threshold applies.
+func (g *DeregistrationGuard) CheckUnregistration(serviceKey ServiceKey, count, clusterTotal int) error {
+	if g == nil || count == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	return g.refuse(serviceKey, g.clusterReason(now, count, clusterTotal), now)
+}
+
+// clusterReason returns why deregistering the count of instances exceeds the cluster threshold, and
+// returns empty if it does not.
+func (g *DeregistrationGuard) clusterReason(now time.Time, count, clusterTotal int) string {
+	if recent := g.recentCount(now) + count; g.clusterThreshold.Exceeded(recent, clusterTotal) {
+		return fmt.Sprintf("%d of %d instances of all services deregistered within %s exceed the threshold",
+			recent, clusterTotal, DefaultDeregistrationWindow)
+	}
+	return ""
+}
+
+// refuse returns the error of the deregistration refused for the reason, and returns nil if the reason
+// is empty or the deregistration has been delayed long enough.
+func (g *DeregistrationGuard) refuse(serviceKey ServiceKey, reason string, now time.Time) error {
+	if reason == "" {
+		delete(g.refused, serviceKey)
+		return nil
+	}
+
+	refusedAt, exist := g.refused[serviceKey]
+	if !exist {
+		refusedAt = now
+		g.refused[serviceKey] = now
+	}
+	if g.delay > 0 && now.Sub(refusedAt) >= g.delay {
+		delete(g.refused, serviceKey)
+		return nil
+	}
+
+	err := &DeregistrationRefusedError{Reason: reason}
+	if g.delay > 0 {
+		err.RetryAfter = g.delay - now.Sub(refusedAt)
+	}
+	return err
+}
+
+// Record records the instances deregistered, which are limited by the cluster threshold.
+func (g *DeregistrationGuard) Record(count int) {
+	if g == nil || count == 0 {
+		return
+	}
+
+	g.recent = append(g.recent, deregistration{at: time.Now(), count: count})
+}
+
+// Forget forgets the refused deregistration of service, such as when the service is unregistered.
+func (g *DeregistrationGuard) Forget(serviceKey ServiceKey) {
+	if g == nil {
+		return
+	}
+
+	delete(g.refused, serviceKey)
+}
+
+// recentCount returns the count of instances deregistered within the window, and forgets the
+// deregistrations out of the window.
+func (g *DeregistrationGuard) recentCount(now time.Time) int {
+	i := 0
+	for i < len(g.recent) && now.Sub(g.recent[i].at) > DefaultDeregistrationWindow {
+		i++
+	}
+	g.recent = g.recent[i:]
+
+	count := 0
+	for _, d := range g.recent {
+		count += d.count
+	}
+	return count
+}
@@ -0,0 +1,255 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    Threshold
+		wantErr bool
+	}{
+		{raw: "", want: Threshold{}},
+		{raw: "10", want: Threshold{value: 10, enabled: true}},
+		{raw: "0", want: Threshold{value: 0, enabled: true}},
+		{raw: "50%", want: Threshold{value: 50, percent: true, enabled: true}},
+		{raw: "12.5%", want: Threshold{value: 12.5, percent: true, enabled: true}},
+		{raw: "100%", want: Threshold{value: 100, percent: true, enabled: true}},
+		{raw: "101%", wantErr: true},
+		{raw: "-1", wantErr: true},
+		{raw: "%", wantErr: true},
+		{raw: "ten", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.raw, func(t *testing.T) {
+			got, err := ParseThreshold(c.raw)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err %v, want error %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestThresholdExceeded(t *testing.T) {
+	cases := []struct {
+		name   string
+		raw    string
+		count  int
+		total  int
+		exceed bool
+	}{
+		{name: "disabled", raw: "", count: 100, total: 100},
+		{name: "number below", raw: "3", count: 2, total: 100},
+		{name: "number equal", raw: "3", count: 3, total: 100},
+		{name: "number above", raw: "3", count: 4, total: 100, exceed: true},
+		{name: "zero", raw: "0", count: 1, total: 100, exceed: true},
+		{name: "percent equal", raw: "50%", count: 5, total: 10},
+		{name: "percent above", raw: "50%", count: 6, total: 10, exceed: true},
+		{name: "percent of fraction", raw: "50%", count: 2, total: 3, exceed: true},
+		{name: "percent of empty", raw: "50%", count: 1, total: 0, exceed: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			threshold, err := ParseThreshold(c.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := threshold.Exceeded(c.count, c.total); got != c.exceed {
+				t.Errorf("got %v, want %v", got, c.exceed)
+			}
+		})
+	}
+}
+
+func TestDeregistrationGuardCheck(t *testing.T) {
+	key := ServiceKey{ServiceName: "foo", Group: "bar"}
+
+	cases := []struct {
+		name    string
+		options GuardOptions
+		// refusedBefore is how long ago the deregistration was refused first, and zero means never.
+		refusedBefore time.Duration
+		// recent is the count of instances deregistered within the window before.
+		recent       int
+		count        int
+		total        int
+		clusterTotal int
+		refused      bool
+		retryAfter   bool
+	}{
+		{
+			name:         "service number within",
+			options:      GuardOptions{ServiceThreshold: "2"},
+			count:        2,
+			total:        10,
+			clusterTotal: 100,
+		},
+		{
+			name:         "service number exceeded",
+			options:      GuardOptions{ServiceThreshold: "2"},
+			count:        3,
+			total:        10,
+			clusterTotal: 100,
+			refused:      true,
+		},
+		{
+			name:         "service percent within",
+			options:      GuardOptions{ServiceThreshold: "50%"},
+			count:        5,
+			total:        10,
+			clusterTotal: 100,
+		},
+		{
+			name:         "service percent exceeded",
+			options:      GuardOptions{ServiceThreshold: "50%"},
+			count:        6,
+			total:        10,
+			clusterTotal: 100,
+			refused:      true,
+		},
+		{
+			name:         "cluster percent within",
+			options:      GuardOptions{ClusterThreshold: "10%"},
+			recent:       5,
+			count:        5,
+			total:        10,
+			clusterTotal: 100,
+		},
+		{
+			name:         "cluster percent exceeded with recent",
+			options:      GuardOptions{ClusterThreshold: "10%"},
+			recent:       6,
+			count:        5,
+			total:        10,
+			clusterTotal: 100,
+			refused:      true,
+		},
+		{
+			name:         "delayed",
+			options:      GuardOptions{ServiceThreshold: "50%", Delay: time.Minute},
+			count:        6,
+			total:        10,
+			clusterTotal: 100,
+			refused:      true,
+			retryAfter:   true,
+		},
+		{
+			name:          "delay not expired",
+			options:       GuardOptions{ServiceThreshold: "50%", Delay: time.Minute},
+			refusedBefore: 30 * time.Second,
+			count:         6,
+			total:         10,
+			clusterTotal:  100,
+			refused:       true,
+			retryAfter:    true,
+		},
+		{
+			name:          "delay expired",
+			options:       GuardOptions{ServiceThreshold: "50%", Delay: time.Minute},
+			refusedBefore: 2 * time.Minute,
+			count:         6,
+			total:         10,
+			clusterTotal:  100,
+		},
+		{
+			name:          "refused without delay",
+			options:       GuardOptions{ServiceThreshold: "50%"},
+			refusedBefore: time.Hour,
+			count:         6,
+			total:         10,
+			clusterTotal:  100,
+			refused:       true,
+		},
+		{
+			name:         "nothing deregistered",
+			options:      GuardOptions{ServiceThreshold: "0"},
+			total:        10,
+			clusterTotal: 100,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			guard, err := NewDeregistrationGuard(c.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.refusedBefore > 0 {
+				guard.refused[key] = time.Now().Add(-c.refusedBefore)
+			}
+			guard.Record(c.recent)
+
+			err = guard.Check(key, c.count, c.total, c.clusterTotal)
+			if (err != nil) != c.refused {
+				t.Fatalf("err %v, want refused %v", err, c.refused)
+			}
+			if err == nil {
+				if _, exist := guard.refused[key]; exist {
+					t.Errorf("allowed deregistration is still recorded as refused")
+				}
+				return
+			}
+
+			refused, ok := err.(*DeregistrationRefusedError)
+			if !ok {
+				t.Fatalf("err %T, want DeregistrationRefusedError", err)
+			}
+			if (refused.RetryAfter > 0) != c.retryAfter {
+				t.Errorf("retry after %s, want delayed %v", refused.RetryAfter, c.retryAfter)
+			}
+			if c.retryAfter && refused.RetryAfter > c.options.Delay-c.refusedBefore {
+				t.Errorf("retry after %s, want at most %s", refused.RetryAfter, c.options.Delay-c.refusedBefore)
+			}
+		})
+	}
+}
+
+func TestDeregistrationGuardCheckUnregistration(t *testing.T) {
+	key := ServiceKey{ServiceName: "foo", Group: "bar"}
+
+	guard, err := NewDeregistrationGuard(GuardOptions{ServiceThreshold: "50%", ClusterThreshold: "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The whole service is unregistered regardless of the service threshold.
+	if err := guard.CheckUnregistration(key, 8, 100); err != nil {
+		t.Fatalf("unregistration refused, err %v", err)
+	}
+	guard.Record(8)
+
+	// The unregistrations are limited by the cluster threshold within the window.
+	if err := guard.CheckUnregistration(key, 3, 100); err == nil {
+		t.Fatalf("unregistration exceeding cluster threshold allowed")
+	}
+
+	// The deregistrations out of the window are forgotten.
+	guard.recent[0].at = time.Now().Add(-2 * DefaultDeregistrationWindow)
+	if err := guard.CheckUnregistration(key, 3, 100); err != nil {
+		t.Fatalf("unregistration refused after window, err %v", err)
+	}
+}
+
+func TestNilDeregistrationGuard(t *testing.T) {
+	guard, err := NewDeregistrationGuard(GuardOptions{})
+	if err != nil || guard != nil {
+		t.Fatalf("got guard %v and err %v, want nil", guard, err)
+	}
+
+	key := ServiceKey{ServiceName: "foo"}
+	if err := guard.Check(key, 10, 10, 10); err != nil {
+		t.Errorf("nil guard refused, err %v", err)
+	}
+	if err := guard.CheckUnregistration(key, 10, 10); err != nil {
+		t.Errorf("nil guard refused, err %v", err)
+	}
+	guard.Record(10)
+	guard.Forget(key)
+}
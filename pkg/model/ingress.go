@@ -0,0 +1,203 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	// MetadataRouteHosts records the hosts of ingress or route in the metadata of instances.
+	MetadataRouteHosts = "route.hosts"
+
+	// MetadataRoutePaths records the paths of ingress or route in the metadata of instances.
+	MetadataRoutePaths = "route.paths"
+
+	// DefaultRoutePort is the port of http route if the port annotation is absent.
+	DefaultRoutePort = defaultHTTPPort
+
+	defaultHTTPPort = 80
+
+	defaultHTTPSPort = 443
+)
+
+const gatewayGroup = "gateway.networking.k8s.io"
+
+// gatewayVersions are the versions of gateway api supported in order of preference, whose http
+// routes and gateways have the same fields used by syncer.
+var gatewayVersions = []string{"v1", "v1beta1"}
+
+// DiscoverGatewayResources returns the resources of http routes and gateways of the most preferred
+// version served by the cluster. It returns an error if none of the versions is served, such as when
+// the gateway api is not installed.
+func DiscoverGatewayResources(client discovery.DiscoveryInterface) (schema.GroupVersionResource,
+	schema.GroupVersionResource, error) {
+	for _, version := range gatewayVersions {
+		groupVersion := schema.GroupVersion{Group: gatewayGroup, Version: version}
+		resources, err := client.ServerResourcesForGroupVersion(groupVersion.String())
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return schema.GroupVersionResource{}, schema.GroupVersionResource{}, err
+		}
+
+		served := make(map[string]bool, len(resources.APIResources))
+		for _, resource := range resources.APIResources {
+			served[resource.Name] = true
+		}
+		if served["httproutes"] && served["gateways"] {
+			return groupVersion.WithResource("httproutes"), groupVersion.WithResource("gateways"), nil
+		}
+	}
+
+	return schema.GroupVersionResource{}, schema.GroupVersionResource{},
+		fmt.Errorf("http routes and gateways of %s %v are not served", gatewayGroup, gatewayVersions)
+}
+
+// GenerateIngressInfo generates the service info from the annotations of ingress. The port is
+// 443 by default if the ingress has tls, and 80 otherwise.
+func GenerateIngressInfo(ingress *networkingv1.Ingress, options SyncOptions) (ServiceInfo, error) {
+	var defaultPort uint64 = defaultHTTPPort
+	if len(ingress.Spec.TLS) > 0 {
+		defaultPort = defaultHTTPSPort
+	}
+
+	return GenerateObjectInfo(ingress, defaultPort, options)
+}
+
+// ConvertIngressToAddresses builds the addresses from the load balancer of ingress, and records
+// the hosts and paths of ingress in the metadata of instances.
+func ConvertIngressToAddresses(serviceInfo ServiceInfo, ingress *networkingv1.Ingress) []Address {
+	var hosts, paths []string
+	for _, rule := range ingress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			paths = append(paths, path.Path)
+		}
+	}
+
+	return convertLoadBalancerToAddresses(serviceInfo, ingress.Status.LoadBalancer.Ingress, hosts, paths)
+}
+
+// ConvertHTTPRouteToAddresses builds the addresses from the gateways which the http route is attached to,
+// and records the hostnames and paths of route in the metadata of instances.
+func ConvertHTTPRouteToAddresses(serviceInfo ServiceInfo, route *unstructured.Unstructured,
+	gateways []*unstructured.Unstructured) []Address {
+	hosts, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+
+	var paths []string
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	for _, rule := range rules {
+		matches, _, _ := unstructured.NestedSlice(asObject(rule), "matches")
+		for _, match := range matches {
+			if path, ok, _ := unstructured.NestedString(asObject(match), "path", "value"); ok {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	var ingresses []v1.LoadBalancerIngress
+	for _, gateway := range gateways {
+		addresses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "addresses")
+		for _, address := range addresses {
+			value, _, _ := unstructured.NestedString(asObject(address), "value")
+			addressType, _, _ := unstructured.NestedString(asObject(address), "type")
+			if value == "" {
+				continue
+			}
+			if addressType == "Hostname" {
+				ingresses = append(ingresses, v1.LoadBalancerIngress{Hostname: value})
+			} else {
+				ingresses = append(ingresses, v1.LoadBalancerIngress{IP: value})
+			}
+		}
+	}
+
+	return convertLoadBalancerToAddresses(serviceInfo, ingresses, hosts, paths)
+}
+
+// HTTPRouteParentGateways returns the namespaced names of gateways which the http route is attached to.
+func HTTPRouteParentGateways(route *unstructured.Unstructured) []string {
+	var gateways []string
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	for _, parentRef := range parentRefs {
+		ref := asObject(parentRef)
+		if kind, ok, _ := unstructured.NestedString(ref, "kind"); ok && kind != "Gateway" {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(ref, "name")
+		namespace, ok, _ := unstructured.NestedString(ref, "namespace")
+		if !ok || namespace == "" {
+			namespace = route.GetNamespace()
+		}
+		gateways = append(gateways, namespace+"/"+name)
+	}
+
+	return gateways
+}
+
+func convertLoadBalancerToAddresses(serviceInfo ServiceInfo, ingresses []v1.LoadBalancerIngress,
+	hosts, paths []string) []Address {
+	metadata := make(map[string]string)
+	if hosts = uniqueSorted(hosts); len(hosts) > 0 {
+		metadata[MetadataRouteHosts] = strings.Join(hosts, ",")
+	}
+	if paths = uniqueSorted(paths); len(paths) > 0 {
+		metadata[MetadataRoutePaths] = strings.Join(paths, ",")
+	}
+
+	var addresses []Address
+	for _, ingress := range ingresses {
+		ip := ingress.IP
+		if ip == "" {
+			ip = ingress.Hostname
+		}
+		if ip == "" {
+			continue
+		}
+
+		addresses = append(addresses, Address{
+			IP:          ip,
+			Port:        serviceInfo.Port,
+			Healthy:     true,
+			Enable:      true,
+			Weight:      serviceInfo.InstanceWeight(),
+			ClusterName: serviceInfo.ClusterName,
+			Metadata:    metadata,
+		})
+	}
+
+	return addresses
+}
+
+func uniqueSorted(values []string) []string {
+	set := make(map[string]struct{}, len(values))
+	var unique []string
+	for _, value := range values {
+		if _, exist := set[value]; exist || value == "" {
+			continue
+		}
+		set[value] = struct{}{}
+		unique = append(unique, value)
+	}
+	sort.Strings(unique)
+
+	return unique
+}
+
+func asObject(value interface{}) map[string]interface{} {
+	object, _ := value.(map[string]interface{})
+	return object
+}
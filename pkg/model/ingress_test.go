@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConvertIngressToAddressesWeight(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        float64
+		wantErr     bool
+	}{
+		{name: "default", want: DefaultNacosEndpointWeight},
+		{name: "annotated", annotations: map[string]string{"nacos.io/instance-weight": "20"}, want: 20},
+		{name: "invalid", annotations: map[string]string{"nacos.io/instance-weight": "-1"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Annotations: c.annotations},
+				Status: networkingv1.IngressStatus{LoadBalancer: v1.LoadBalancerStatus{
+					Ingress: []v1.LoadBalancerIngress{{IP: "10.0.0.1"}},
+				}},
+			}
+
+			info, err := GenerateIngressInfo(ingress, SyncOptions{})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err %v, want error %v", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			addresses := ConvertIngressToAddresses(info, ingress)
+			if len(addresses) != 1 || addresses[0].Weight != c.want {
+				t.Errorf("got %+v, want weight %v", addresses, c.want)
+			}
+		})
+	}
+}
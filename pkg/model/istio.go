@@ -0,0 +1,200 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nacos-group/nacos-sdk-go/common/constant"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// LabelIstioService selects the workload entries of the service entry converted from nacos service.
+	LabelIstioService = "nacos.io/service-entry"
+
+	// LabelManagedBy marks the resources maintained by syncer.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+
+	// ManagedBy is the value of managed-by label of the resources maintained by syncer.
+	ManagedBy = "nacos-k8s-sync"
+
+	// metadataProtocol specifies the istio protocol of instance port.
+	metadataProtocol = "protocol"
+
+	defaultIstioProtocol = "TCP"
+
+	defaultNacosCluster = "DEFAULT"
+
+	// istioWeightScale scales the weights of nacos, which are floats, into the integral ones of istio.
+	istioWeightScale = 100
+)
+
+var (
+	ServiceEntryResource = schema.GroupVersionResource{
+		Group:    "networking.istio.io",
+		Version:  "v1beta1",
+		Resource: "serviceentries",
+	}
+
+	WorkloadEntryResource = schema.GroupVersionResource{
+		Group:    "networking.istio.io",
+		Version:  "v1beta1",
+		Resource: "workloadentries",
+	}
+
+	istioProtocols = map[string]struct{}{
+		"HTTP": {}, "HTTPS": {}, "HTTP2": {}, "GRPC": {}, "MONGO": {}, "TCP": {}, "TLS": {},
+	}
+
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// IstioResourceName returns the name of service entry converted from the nacos service. A short hash
+// of the service key is appended, because different keys may be sanitized into the same name.
+func IstioResourceName(serviceKey ServiceKey) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(
+		strings.ToLower("nacos-"+groupOf(serviceKey)+"-"+serviceKey.ServiceName), "-"), "-")
+	return hashedName(name, serviceKey.ServiceName+"@@"+groupOf(serviceKey))
+}
+
+// IstioHost returns the host of service entry converted from the nacos service.
+func IstioHost(serviceKey ServiceKey, suffix string) string {
+	host := sanitizeName(serviceKey.ServiceName) + "." + sanitizeName(groupOf(serviceKey))
+	if suffix != "" {
+		host += "." + suffix
+	}
+
+	return host
+}
+
+// ConvertToServiceEntry builds the istio service entry of the nacos service, whose ports are
+// the union of the ports of instances.
+func ConvertToServiceEntry(serviceKey ServiceKey, namespace, hostSuffix string, addresses []Address) *unstructured.Unstructured {
+	name := IstioResourceName(serviceKey)
+	protocols := make(map[uint64]string)
+	for _, address := range addresses {
+		if _, exist := protocols[address.Port]; !exist {
+			protocols[address.Port] = istioProtocol(address)
+		}
+	}
+
+	ports := make([]uint64, 0, len(protocols))
+	for port := range protocols {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+
+	var specPorts []interface{}
+	for _, port := range ports {
+		specPorts = append(specPorts, map[string]interface{}{
+			"number":   int64(port),
+			"name":     fmt.Sprintf("%s-%d", strings.ToLower(protocols[port]), port),
+			"protocol": protocols[port],
+		})
+	}
+
+	serviceEntry := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"hosts":      []interface{}{IstioHost(serviceKey, hostSuffix)},
+			"location":   "MESH_INTERNAL",
+			"resolution": "STATIC",
+			"ports":      specPorts,
+			"workloadSelector": map[string]interface{}{
+				"labels": map[string]interface{}{
+					LabelIstioService: name,
+				},
+			},
+		},
+	}}
+	serviceEntry.SetAPIVersion(ServiceEntryResource.GroupVersion().String())
+	serviceEntry.SetKind("ServiceEntry")
+	serviceEntry.SetName(name)
+	serviceEntry.SetNamespace(namespace)
+	serviceEntry.SetLabels(map[string]string{LabelManagedBy: ManagedBy})
+
+	return serviceEntry
+}
+
+// ConvertToWorkloadEntries builds the istio workload entries of the available instances. The weight,
+// metadata and cluster of instances are mapped to the weight, labels and locality of workload entries.
+func ConvertToWorkloadEntries(serviceKey ServiceKey, namespace string, addresses []Address) []*unstructured.Unstructured {
+	serviceEntryName := IstioResourceName(serviceKey)
+
+	var workloadEntries []*unstructured.Unstructured
+	for _, address := range addresses {
+		if !address.Healthy || !address.Enable || address.Weight <= 0 {
+			continue
+		}
+
+		labels := map[string]interface{}{}
+		for key, value := range address.Metadata {
+			if len(validation.IsQualifiedName(key)) == 0 && len(validation.IsValidLabelValue(value)) == 0 {
+				labels[key] = value
+			}
+		}
+		labels[LabelIstioService] = serviceEntryName
+
+		spec := map[string]interface{}{
+			"address": address.IP,
+			"weight":  istioWeight(address.Weight),
+			"labels":  labels,
+		}
+		if address.ClusterName != "" && address.ClusterName != defaultNacosCluster {
+			spec["locality"] = address.ClusterName
+		}
+
+		workloadEntry := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": spec,
+		}}
+		workloadEntry.SetAPIVersion(WorkloadEntryResource.GroupVersion().String())
+		workloadEntry.SetKind("WorkloadEntry")
+		workloadEntry.SetName(sanitizeName(fmt.Sprintf("%s-%s-%d", serviceEntryName, address.IP, address.Port)))
+		workloadEntry.SetNamespace(namespace)
+		workloadEntry.SetLabels(map[string]string{
+			LabelManagedBy:    ManagedBy,
+			LabelIstioService: serviceEntryName,
+		})
+		workloadEntries = append(workloadEntries, workloadEntry)
+	}
+
+	return workloadEntries
+}
+
+func istioProtocol(address Address) string {
+	protocol := strings.ToUpper(address.Metadata[metadataProtocol])
+	if _, ok := istioProtocols[protocol]; ok {
+		return protocol
+	}
+
+	return defaultIstioProtocol
+}
+
+func groupOf(serviceKey ServiceKey) string {
+	if serviceKey.Group == "" {
+		return constant.DEFAULT_GROUP
+	}
+
+	return serviceKey.Group
+}
+
+// istioWeight converts the weight of nacos into the integral weight of istio. The weights are scaled
+// to keep the fractional part, and the positive ones are at least 1.
+func istioWeight(weight float64) int64 {
+	scaled := int64(math.Round(weight * istioWeightScale))
+	if scaled < 1 {
+		scaled = 1
+	}
+
+	return scaled
+}
+
+// sanitizeName converts the value into a valid name of k8s resource, which is truncated with a short
+// hash of the full value if it is too long.
+func sanitizeName(value string) string {
+	return truncateName(strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(value), "-"), "-"))
+}
@@ -1,11 +1,30 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 )
 
+// EventComponent is the source of the events recorded by syncer.
+const EventComponent = "nacos-k8s-sync"
+
 type Task struct {
 	Handler func() error
 }
@@ -14,17 +33,172 @@ type KubeOptions struct {
 	KubeConfig string
 
 	WatchedNamespace string
+
+	// LabelSelector restricts the services and endpoints watched by syncer to the matched ones.
+	LabelSelector string
+
+	// IncludeNamespaces are the namespaces whose services are watched, and all namespaces are
+	// watched if it is empty.
+	IncludeNamespaces []string
+
+	// ExcludeNamespaces are the namespaces whose services are never watched.
+	ExcludeNamespaces []string
+
+	// RemoteClusters are the other k8s clusters whose services are synced to nacos too,
+	// formatted as clusterID=kubeconfig or clusterID=kubeconfig#context.
+	RemoteClusters []string
+
+	// RemoteSecretNamespace is the namespace of the secrets labeled by <annotation prefix>/remote-cluster,
+	// whose data are the kubeconfigs of remote clusters keyed by the cluster id.
+	RemoteSecretNamespace string
+}
+
+// ServiceWatched returns whether the service is watched, which is in the namespaces watched and
+// selected by the label selector. The invalid label selector selects nothing.
+func (o KubeOptions) ServiceWatched(service metav1.Object) bool {
+	if !o.NamespaceWatched(service.GetNamespace()) {
+		return false
+	}
+
+	selector, err := labels.Parse(o.LabelSelector)
+	return err == nil && selector.Matches(labels.Set(service.GetLabels()))
+}
+
+// WithFilters returns the options whose filters of services, which can be changed at runtime, are
+// replaced with the ones of other.
+func (o KubeOptions) WithFilters(other KubeOptions) KubeOptions {
+	o.LabelSelector = other.LabelSelector
+	o.IncludeNamespaces = other.IncludeNamespaces
+	o.ExcludeNamespaces = other.ExcludeNamespaces
+	return o
+}
+
+// NamespaceWatched returns whether the services in the namespace are watched.
+func (o KubeOptions) NamespaceWatched(namespace string) bool {
+	if o.WatchedNamespace != v1.NamespaceAll && namespace != o.WatchedNamespace {
+		return false
+	}
+	for _, excluded := range o.ExcludeNamespaces {
+		if namespace == excluded {
+			return false
+		}
+	}
+	if len(o.IncludeNamespaces) == 0 {
+		return true
+	}
+	for _, included := range o.IncludeNamespaces {
+		if namespace == included {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceNamespace returns the only namespace which the services are watched in, and returns
+// NamespaceAll if they are watched in more than one namespace.
+func (o KubeOptions) serviceNamespace() string {
+	if o.WatchedNamespace == v1.NamespaceAll && len(o.IncludeNamespaces) == 1 {
+		return o.IncludeNamespaces[0]
+	}
+	return o.WatchedNamespace
+}
+
+// serviceListOptions returns the function which restricts the lists and watches of services with
+// the label selector and the excluded namespaces, so that the filtered ones are never cached.
+func (o KubeOptions) serviceListOptions() (func(*metav1.ListOptions), error) {
+	if _, err := labels.Parse(o.LabelSelector); err != nil {
+		return nil, fmt.Errorf("invalid label selector %s, err %v", o.LabelSelector, err)
+	}
+
+	fieldSelector := o.namespaceFieldSelector()
+	return func(options *metav1.ListOptions) {
+		options.LabelSelector = o.LabelSelector
+		options.FieldSelector = fieldSelector
+	}, nil
+}
+
+// podListOptions returns the function which restricts the lists and watches of pods with the
+// excluded namespaces. The label selector selects services, so the pods are not filtered by it.
+func (o KubeOptions) podListOptions() func(*metav1.ListOptions) {
+	fieldSelector := o.namespaceFieldSelector()
+	return func(options *metav1.ListOptions) {
+		options.FieldSelector = fieldSelector
+	}
+}
+
+func (o KubeOptions) namespaceFieldSelector() string {
+	// The field selector can not select multiple namespaces, so the namespaces which are not
+	// included are filtered by NamespaceWatched.
+	var selectors []fields.Selector
+	for _, namespace := range o.ExcludeNamespaces {
+		selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", namespace))
+	}
+	return fields.AndSelectors(selectors...).String()
+}
+
+// ScopedInformerFactories are the informer factories restricted by the filters of services, which are
+// replaced as a whole when the filters change at runtime.
+type ScopedInformerFactories struct {
+	// Service lists and watches the services and endpoints restricted by the label selector and
+	// the namespaces.
+	Service informers.SharedInformerFactory
+
+	// Pod lists and watches the pods restricted by the namespaces.
+	Pod informers.SharedInformerFactory
+}
+
+// NewScopedInformerFactories creates the informer factories restricted by the filters of options.
+func NewScopedInformerFactories(client kubernetes.Interface, option KubeOptions) (ScopedInformerFactories, error) {
+	tweakListOptions, err := option.serviceListOptions()
+	if err != nil {
+		return ScopedInformerFactories{}, err
+	}
+
+	return ScopedInformerFactories{
+		Service: informers.NewSharedInformerFactoryWithOptions(client, DefaultResyncInterval,
+			informers.WithNamespace(option.serviceNamespace()), informers.WithTweakListOptions(tweakListOptions)),
+		Pod: informers.NewSharedInformerFactoryWithOptions(client, DefaultResyncInterval,
+			informers.WithNamespace(option.serviceNamespace()), informers.WithTweakListOptions(option.podListOptions())),
+	}, nil
+}
+
+// Start starts the informers requested from the factories.
+func (f ScopedInformerFactories) Start(stop <-chan struct{}) {
+	f.Service.Start(stop)
+	f.Pod.Start(stop)
 }
 
 type KubeClient interface {
+	// Kubernetes returns the client to write k8s resources.
+	Kubernetes() kubernetes.Interface
+
+	// Dynamic returns the client to write the resources which have no typed client.
+	Dynamic() dynamic.Interface
+
 	// KubeInformer returns an informer factory for kube client
 	InformerFactory() informers.SharedInformerFactory
 
+	// ServiceInformerFactory returns an informer factory for the services and endpoints synced to
+	// nacos, which are restricted by the label selector and namespaces of options.
+	ServiceInformerFactory() informers.SharedInformerFactory
+
+	// DynamicInformerFactory returns an informer factory for the resources which have no typed client,
+	// such as the resources of gateway api.
+	DynamicInformerFactory() dynamicinformer.DynamicSharedInformerFactory
+
 	Run(<-chan struct{})
 }
 
 type kubeClient struct {
+	client kubernetes.Interface
+
+	dynamicClient dynamic.Interface
+
 	informerFactory informers.SharedInformerFactory
+
+	serviceInformerFactory informers.SharedInformerFactory
+
+	dynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory
 }
 
 func NewKubeClient(option KubeOptions) (KubeClient, error) {
@@ -33,24 +207,95 @@ func NewKubeClient(option KubeOptions) (KubeClient, error) {
 		return nil, err
 	}
 
+	return NewKubeClientForConfig(kubeConfig, option)
+}
+
+// NewKubeClientForConfig creates the kube client of the cluster which the config points to.
+func NewKubeClientForConfig(kubeConfig *rest.Config, option KubeOptions) (KubeClient, error) {
 	client, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(client, DefaultResyncInterval,
 		informers.WithNamespace(option.WatchedNamespace))
 
+	scopedInformerFactories, err := NewScopedInformerFactories(client, option)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	dynamicInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient,
+		DefaultResyncInterval, option.WatchedNamespace, nil)
+
 	return &kubeClient{
-		informerFactory: informerFactory,
+		client:                 client,
+		dynamicClient:          dynamicClient,
+		informerFactory:        informerFactory,
+		serviceInformerFactory: scopedInformerFactories.Service,
+		dynamicInformerFactory: dynamicInformerFactory,
 	}, nil
 }
 
+func (k *kubeClient) Kubernetes() kubernetes.Interface {
+	return k.client
+}
+
+func (k *kubeClient) Dynamic() dynamic.Interface {
+	return k.dynamicClient
+}
+
 func (k *kubeClient) InformerFactory() informers.SharedInformerFactory {
 	return k.informerFactory
 }
 
+func (k *kubeClient) ServiceInformerFactory() informers.SharedInformerFactory {
+	return k.serviceInformerFactory
+}
+
+func (k *kubeClient) DynamicInformerFactory() dynamicinformer.DynamicSharedInformerFactory {
+	return k.dynamicInformerFactory
+}
+
 func (k *kubeClient) Run(stop <-chan struct{}) {
 	go k.informerFactory.Start(stop)
+	go k.serviceInformerFactory.Start(stop)
+	go k.dynamicInformerFactory.Start(stop)
+}
+
+// NewEventRecorder creates the recorder which records the events of k8s objects, such as the
+// errors of syncing a service.
+func NewEventRecorder(client kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: EventComponent})
+}
+
+// nameHashLength is the length of the hash appended to the truncated names.
+const nameHashLength = 8
+
+// truncateName truncates the name of k8s resource to the max length of dns label. A short hash of
+// the full name is appended to the truncated one, so that the long names sharing a prefix do not
+// collide.
+func truncateName(name string) string {
+	if len(name) <= validation.DNS1123LabelMaxLength {
+		return name
+	}
+
+	return hashedName(name, name)
+}
+
+// hashedName appends a short hash of the key to the name, and the name is truncated to keep the
+// result within the max length of dns label.
+func hashedName(name, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	if maxLength := validation.DNS1123LabelMaxLength - nameHashLength - 1; len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "-.")
+	}
+	return name + "-" + hex.EncodeToString(sum[:])[:nameHashLength]
 }
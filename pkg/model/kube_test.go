@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestListOptions(t *testing.T) {
+	options := KubeOptions{LabelSelector: "app=foo", ExcludeNamespaces: []string{"kube-system"}}
+
+	serviceListOptions, err := options.serviceListOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services metav1.ListOptions
+	serviceListOptions(&services)
+	if services.LabelSelector != "app=foo" || services.FieldSelector != "metadata.namespace!=kube-system" {
+		t.Errorf("got service list options %+v", services)
+	}
+
+	// The pods of the selected services do not carry the labels of services.
+	var pods metav1.ListOptions
+	options.podListOptions()(&pods)
+	if pods.LabelSelector != "" || pods.FieldSelector != "metadata.namespace!=kube-system" {
+		t.Errorf("got pod list options %+v", pods)
+	}
+
+	if _, err := (KubeOptions{LabelSelector: "app in"}).serviceListOptions(); err == nil {
+		t.Errorf("invalid label selector accepted")
+	}
+}
+
+func TestServiceWatched(t *testing.T) {
+	service := &metav1.ObjectMeta{Name: "foo", Namespace: "a", Labels: map[string]string{"app": "foo"}}
+
+	cases := []struct {
+		name    string
+		options KubeOptions
+		watched bool
+	}{
+		{name: "no filter", watched: true},
+		{name: "selected", options: KubeOptions{LabelSelector: "app=foo"}, watched: true},
+		{name: "not selected", options: KubeOptions{LabelSelector: "app=bar"}},
+		{name: "invalid selector", options: KubeOptions{LabelSelector: "app in"}},
+		{name: "included", options: KubeOptions{IncludeNamespaces: []string{"a"}}, watched: true},
+		{name: "not included", options: KubeOptions{IncludeNamespaces: []string{"b"}}},
+		{name: "excluded", options: KubeOptions{ExcludeNamespaces: []string{"a"}}},
+		{
+			name:    "filters replaced",
+			options: KubeOptions{ExcludeNamespaces: []string{"a"}}.WithFilters(KubeOptions{LabelSelector: "app=foo"}),
+			watched: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.options.ServiceWatched(service); got != c.watched {
+				t.Errorf("got %v, want %v", got, c.watched)
+			}
+		})
+	}
+}
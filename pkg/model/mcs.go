@@ -0,0 +1,217 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// LabelMCSServiceName is the name of service import which the endpoint slice belongs to.
+	LabelMCSServiceName = "multicluster.kubernetes.io/service-name"
+
+	// LabelMCSSourceCluster is the id of cluster which the endpoints of slice come from.
+	LabelMCSSourceCluster = "multicluster.kubernetes.io/source-cluster"
+)
+
+var (
+	ServiceExportResource = schema.GroupVersionResource{
+		Group:    "multicluster.x-k8s.io",
+		Version:  "v1alpha1",
+		Resource: "serviceexports",
+	}
+
+	ServiceImportResource = schema.GroupVersionResource{
+		Group:    "multicluster.x-k8s.io",
+		Version:  "v1alpha1",
+		Resource: "serviceimports",
+	}
+)
+
+// MCSServiceName returns the name of nacos service exported by multi-cluster services api,
+// which keeps the namespace sameness of k8s services.
+func MCSServiceName(namespace, name string) string {
+	return name + "." + namespace
+}
+
+// ParseMCSServiceName returns the namespace and name of k8s service from the name of nacos service.
+func ParseMCSServiceName(serviceName string) (string, string, bool) {
+	parts := strings.SplitN(serviceName, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[1], parts[0], true
+}
+
+// GenerateExportServiceInfo generates the service info of k8s service exported by ServiceExport.
+// The annotations are still respected, but the service is always registered in the mcs group with
+// the mcs name, and the port is the target port of the first service port by default.
+func GenerateExportServiceInfo(svc *v1.Service, options SyncOptions) (ServiceInfo, error) {
+	if len(svc.Spec.Ports) == 0 {
+		return ServiceInfo{}, fmt.Errorf("exported service (%s:%s) has no port", svc.Name, svc.Namespace)
+	}
+
+	defaultPort := uint64(svc.Spec.Ports[0].TargetPort.IntValue())
+	if defaultPort == 0 {
+		defaultPort = uint64(svc.Spec.Ports[0].Port)
+	}
+
+	serviceInfo, err := GenerateObjectInfo(svc, defaultPort, options)
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+
+	serviceInfo.ServiceKey = ServiceKey{
+		ServiceName: MCSServiceName(svc.Namespace, svc.Name),
+		Group:       options.MCSGroup,
+	}
+	if serviceInfo.Metadata == nil {
+		serviceInfo.Metadata = make(map[string]string, 1)
+	}
+	serviceInfo.Metadata[MetadataKubePortProtocol] = string(exportedPortProtocol(svc, serviceInfo.Port))
+	return serviceInfo, nil
+}
+
+// exportedPortProtocol returns the protocol of the service port whose target port or port is the
+// registered port, and the protocol of the first service port by default.
+func exportedPortProtocol(svc *v1.Service, port uint64) v1.Protocol {
+	protocol := svc.Spec.Ports[0].Protocol
+	for _, servicePort := range svc.Spec.Ports {
+		if uint64(servicePort.TargetPort.IntValue()) == port || uint64(servicePort.Port) == port {
+			protocol = servicePort.Protocol
+			break
+		}
+	}
+	if protocol == "" {
+		protocol = v1.ProtocolTCP
+	}
+	return protocol
+}
+
+// mcsPortKey is the port of ServiceImport, and the endpoint slices of it are grouped by the key.
+type mcsPortKey struct {
+	port     uint64
+	protocol v1.Protocol
+}
+
+// mcsPortProtocol returns the protocol recorded by the exporting syncer, and TCP by default.
+func mcsPortProtocol(address Address) v1.Protocol {
+	switch protocol := v1.Protocol(strings.ToUpper(address.Metadata[MetadataKubePortProtocol])); protocol {
+	case v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP:
+		return protocol
+	default:
+		return v1.ProtocolTCP
+	}
+}
+
+// ConvertToServiceImport builds the ServiceImport of the service exported by other clusters,
+// whose ports are the union of the ports and protocols of instances.
+func ConvertToServiceImport(namespace, name string, addresses []Address) *unstructured.Unstructured {
+	set := make(map[mcsPortKey]struct{})
+	for _, address := range addresses {
+		set[mcsPortKey{port: address.Port, protocol: mcsPortProtocol(address)}] = struct{}{}
+	}
+
+	ports := make([]mcsPortKey, 0, len(set))
+	for key := range set {
+		ports = append(ports, key)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].port != ports[j].port {
+			return ports[i].port < ports[j].port
+		}
+		return ports[i].protocol < ports[j].protocol
+	})
+
+	var specPorts []interface{}
+	for _, key := range ports {
+		specPorts = append(specPorts, map[string]interface{}{
+			"name":     mcsPortName(key),
+			"port":     int64(key.port),
+			"protocol": string(key.protocol),
+		})
+	}
+
+	serviceImport := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"type":  "ClusterSetIP",
+			"ports": specPorts,
+		},
+	}}
+	serviceImport.SetAPIVersion(ServiceImportResource.GroupVersion().String())
+	serviceImport.SetKind("ServiceImport")
+	serviceImport.SetName(name)
+	serviceImport.SetNamespace(namespace)
+	serviceImport.SetLabels(map[string]string{LabelManagedBy: ManagedBy})
+
+	return serviceImport
+}
+
+// ConvertToMCSEndpointSlices builds the endpoint slices of ServiceImport from the instances of other
+// clusters, which are grouped by the cluster id recorded in their metadata.
+func ConvertToMCSEndpointSlices(namespace, name, localClusterID string, owner metav1.OwnerReference,
+	addresses []Address) []*discoveryv1beta1.EndpointSlice {
+	type clusterProtocol struct {
+		clusterID string
+		protocol  v1.Protocol
+	}
+	clusters := make(map[clusterProtocol][]Address)
+	for _, address := range addresses {
+		clusterID := address.Metadata[MetadataKubeCluster]
+		if clusterID == "" || clusterID == localClusterID {
+			continue
+		}
+		cluster := clusterProtocol{clusterID: clusterID, protocol: mcsPortProtocol(address)}
+		clusters[cluster] = append(clusters[cluster], address)
+	}
+
+	var slices []*discoveryv1beta1.EndpointSlice
+	for cluster, clusterAddresses := range clusters {
+		clusterID := cluster.clusterID
+		for key, endpoints := range groupEndpoints(clusterAddresses) {
+			sliceName := fmt.Sprintf("%s-%s-%d", name, clusterID, key.port)
+			// The slices of TCP keep the names without protocol.
+			if cluster.protocol != v1.ProtocolTCP {
+				sliceName += "-" + string(cluster.protocol)
+			}
+			if key.addressType == discoveryv1beta1.AddressTypeIPv6 {
+				sliceName += "-ipv6"
+			}
+			portName := mcsPortName(mcsPortKey{port: key.port, protocol: cluster.protocol})
+			port := int32(key.port)
+			protocol := cluster.protocol
+			slices = append(slices, &discoveryv1beta1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sanitizeName(sliceName),
+					Namespace: namespace,
+					Labels: map[string]string{
+						LabelMCSServiceName:             name,
+						LabelMCSSourceCluster:           clusterID,
+						discoveryv1beta1.LabelManagedBy: EndpointSliceManagedBy,
+					},
+					OwnerReferences: []metav1.OwnerReference{owner},
+				},
+				AddressType: key.addressType,
+				Endpoints:   endpoints,
+				Ports: []discoveryv1beta1.EndpointPort{{
+					Name:     &portName,
+					Port:     &port,
+					Protocol: &protocol,
+				}},
+			})
+		}
+	}
+
+	return slices
+}
+
+func mcsPortName(key mcsPortKey) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(string(key.protocol)), key.port)
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGenerateExportServiceInfoProtocol(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1.ServiceSpec{Ports: []v1.ServicePort{
+			{Port: 53, TargetPort: intstr.FromInt(5353), Protocol: v1.ProtocolUDP},
+			{Port: 80, TargetPort: intstr.FromInt(8080), Protocol: v1.ProtocolTCP},
+		}},
+	}
+
+	info, err := GenerateExportServiceInfo(svc, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Port != 5353 || info.Metadata[MetadataKubePortProtocol] != string(v1.ProtocolUDP) {
+		t.Errorf("got port %d with protocol %q, want 5353 with UDP", info.Port, info.Metadata[MetadataKubePortProtocol])
+	}
+}
+
+func TestConvertToMCSProtocol(t *testing.T) {
+	addresses := []Address{
+		{IP: "10.0.0.1", Port: 5353, Healthy: true, Enable: true, Weight: 1, Metadata: map[string]string{
+			MetadataKubeCluster: "remote", MetadataKubePortProtocol: "UDP",
+		}},
+		{IP: "10.0.0.2", Port: 8080, Healthy: true, Enable: true, Weight: 1, Metadata: map[string]string{
+			MetadataKubeCluster: "remote",
+		}},
+		{IP: "10.0.0.3", Port: 8080, Healthy: true, Enable: true, Weight: 1, Metadata: map[string]string{
+			MetadataKubeCluster: "local",
+		}},
+	}
+
+	serviceImport := ConvertToServiceImport("default", "foo", addresses)
+	ports, _, _ := unstructured.NestedSlice(serviceImport.Object, "spec", "ports")
+	want := []map[string]interface{}{
+		{"name": "udp-5353", "port": int64(5353), "protocol": "UDP"},
+		{"name": "tcp-8080", "port": int64(8080), "protocol": "TCP"},
+	}
+	if len(ports) != len(want) {
+		t.Fatalf("got ports %v, want %v", ports, want)
+	}
+	for i := range want {
+		port := ports[i].(map[string]interface{})
+		for key, value := range want[i] {
+			if port[key] != value {
+				t.Errorf("port %d: got %s %v, want %v", i, key, port[key], value)
+			}
+		}
+	}
+
+	slices := ConvertToMCSEndpointSlices("default", "foo", "local", metav1.OwnerReference{}, addresses)
+	names := make(map[string]v1.Protocol, len(slices))
+	for _, slice := range slices {
+		names[slice.Name] = *slice.Ports[0].Protocol
+	}
+	wantNames := map[string]v1.Protocol{"foo-remote-5353-udp": v1.ProtocolUDP, "foo-remote-8080": v1.ProtocolTCP}
+	if len(names) != len(wantNames) {
+		t.Fatalf("got slices %v, want %v", names, wantNames)
+	}
+	for name, protocol := range wantNames {
+		if names[name] != protocol {
+			t.Errorf("slice %s: got protocol %q, want %q", name, names[name], protocol)
+		}
+	}
+}
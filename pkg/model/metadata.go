@@ -0,0 +1,145 @@
+package model
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/version"
+)
+
+// The reserved metadata keys which describe the k8s identity of instances.
+const (
+	MetadataKubeNamespace = "k8s.namespace"
+
+	MetadataKubeService = "k8s.service"
+
+	MetadataKubePod = "k8s.pod"
+
+	MetadataKubeNode = "k8s.node"
+
+	MetadataKubeCluster = "k8s.cluster"
+
+	// MetadataKubeRoute is the name of ingress or http route which the instance comes from.
+	MetadataKubeRoute = "k8s.route"
+
+	// MetadataKubePortProtocol is the protocol of service port which the instance port serves, so that the
+	// ServiceImport of exported service keeps the protocol.
+	MetadataKubePortProtocol = "k8s.port-protocol"
+
+	MetadataSyncerVersion = "nacos-k8s-sync.version"
+
+	// MetadataOwnedKeys records the keys of metadata owned by syncer, separated by comma, so that
+	// the other keys are preserved as the ones set outside syncer.
+	MetadataOwnedKeys = "nacos-k8s-sync.owned-keys"
+)
+
+// FillIdentityMetadata sets the reserved identity metadata of addresses, which take
+// precedence over the metadata provided by users.
+func FillIdentityMetadata(namespace, serviceName, clusterID string, addresses []Address) {
+	for i := range addresses {
+		identity := map[string]string{
+			MetadataKubeNamespace: namespace,
+			MetadataKubeService:   serviceName,
+			MetadataKubePod:       addresses[i].PodName,
+			MetadataKubeNode:      addresses[i].NodeName,
+			MetadataKubeCluster:   clusterID,
+			MetadataSyncerVersion: version.Version,
+		}
+
+		metadata := make(map[string]string, len(addresses[i].Metadata)+len(identity))
+		for key, value := range addresses[i].Metadata {
+			metadata[key] = value
+		}
+		for key, value := range identity {
+			if value != "" {
+				metadata[key] = value
+			}
+		}
+		addresses[i].Metadata = metadata
+	}
+}
+
+// IsOwnInstance returns whether the address is registered by syncer for the given k8s service,
+// which must not be imported into the same service again.
+func IsOwnInstance(address Address, namespace, serviceName, clusterID string) bool {
+	return address.Metadata[MetadataKubeNamespace] == namespace &&
+		address.Metadata[MetadataKubeService] == serviceName &&
+		address.Metadata[MetadataKubeCluster] == clusterID
+}
+
+// ServiceIdentity returns the identity metadata carried by all the instances of the k8s service.
+func ServiceIdentity(namespace, serviceName, clusterID string) map[string]string {
+	return map[string]string{
+		MetadataKubeNamespace: namespace,
+		MetadataKubeService:   serviceName,
+		MetadataKubeCluster:   clusterID,
+	}
+}
+
+// RouteIdentity returns the identity metadata carried by all the instances of the ingress or route.
+func RouteIdentity(namespace, routeName, clusterID string) map[string]string {
+	return map[string]string{
+		MetadataKubeNamespace: namespace,
+		MetadataKubeService:   "",
+		MetadataKubeRoute:     routeName,
+		MetadataKubeCluster:   clusterID,
+	}
+}
+
+// PodIdentity returns the identity metadata carried by all the instances of pods synced without
+// k8s service.
+func PodIdentity(clusterID string) map[string]string {
+	return map[string]string{
+		MetadataKubeService: "",
+		MetadataKubeRoute:   "",
+		MetadataKubeCluster: clusterID,
+	}
+}
+
+// IsOwnedInstance returns whether the address is registered by syncer with the identity, and the
+// empty values of identity require the keys to be absent.
+func IsOwnedInstance(address Address, identity map[string]string) bool {
+	if len(identity) == 0 || address.Metadata[MetadataSyncerVersion] == "" {
+		return false
+	}
+
+	for key, value := range identity {
+		if address.Metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeForeignMetadata merges the desired metadata of syncer with the existing ones of the instance
+// in nacos according to the policy.
+func MergeForeignMetadata(policy MetadataPolicy, existing, desired map[string]string) map[string]string {
+	switch policy {
+	case MetadataMerge:
+		return MergeMetadata(existing, desired)
+	case MetadataPreserveForeignKeys:
+		merged := make(map[string]string, len(existing)+len(desired)+1)
+		owned := make(map[string]struct{})
+		if raw := existing[MetadataOwnedKeys]; raw != "" {
+			for _, key := range strings.Split(raw, ",") {
+				owned[key] = struct{}{}
+			}
+		}
+		for key, value := range existing {
+			if _, exist := owned[key]; !exist && key != MetadataOwnedKeys {
+				merged[key] = value
+			}
+		}
+
+		keys := make([]string, 0, len(desired))
+		for key, value := range desired {
+			merged[key] = value
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		merged[MetadataOwnedKeys] = strings.Join(keys, ",")
+		return merged
+	default:
+		return desired
+	}
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeForeignMetadata(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   MetadataPolicy
+		existing map[string]string
+		desired  map[string]string
+		want     map[string]string
+	}{
+		{
+			name:     "overwrite",
+			policy:   MetadataOverwrite,
+			existing: map[string]string{"foreign": "x", "app": "old"},
+			desired:  map[string]string{"app": "new"},
+			want:     map[string]string{"app": "new"},
+		},
+		{
+			name:     "merge keeps removed keys",
+			policy:   MetadataMerge,
+			existing: map[string]string{"foreign": "x", "app": "old", "version": "v1"},
+			desired:  map[string]string{"app": "new"},
+			want:     map[string]string{"foreign": "x", "app": "new", "version": "v1"},
+		},
+		{
+			name:     "preserve foreign keys on first registration",
+			policy:   MetadataPreserveForeignKeys,
+			existing: map[string]string{"foreign": "x", "app": "old"},
+			desired:  map[string]string{"app": "new", "version": "v1"},
+			want: map[string]string{
+				"foreign": "x", "app": "new", "version": "v1",
+				MetadataOwnedKeys: "app,version",
+			},
+		},
+		{
+			name:   "preserve foreign keys removes owned keys",
+			policy: MetadataPreserveForeignKeys,
+			existing: map[string]string{
+				"foreign": "x", "app": "old", "version": "v1",
+				MetadataOwnedKeys: "app,version",
+			},
+			desired: map[string]string{"app": "new"},
+			want: map[string]string{
+				"foreign": "x", "app": "new",
+				MetadataOwnedKeys: "app",
+			},
+		},
+		{
+			name:   "preserve foreign keys removes all owned keys",
+			policy: MetadataPreserveForeignKeys,
+			existing: map[string]string{
+				"foreign": "x", "app": "old",
+				MetadataOwnedKeys: "app",
+			},
+			want: map[string]string{
+				"foreign":         "x",
+				MetadataOwnedKeys: "",
+			},
+		},
+		{
+			name:    "preserve foreign keys without instance",
+			policy:  MetadataPreserveForeignKeys,
+			desired: map[string]string{"app": "new"},
+			want:    map[string]string{"app": "new", MetadataOwnedKeys: "app"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := MergeForeignMetadata(c.policy, c.existing, c.desired); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,14 @@ package model
 import (
 	"os"
 	"path"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/nacos-group/nacos-sdk-go/clients"
 	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/common/constant"
+	nacosmodel "github.com/nacos-group/nacos-sdk-go/model"
 	"github.com/nacos-group/nacos-sdk-go/vo"
 	v1 "k8s.io/api/core/v1"
 
@@ -48,6 +52,24 @@ type ServiceKey struct {
 	ServiceName string
 
 	Group string
+
+	// Namespace is the nacos namespace of the service. Empty means the namespace of syncer.
+	Namespace string
+}
+
+func (k ServiceKey) String() string {
+	return k.ServiceName + "@@" + k.Group
+}
+
+// ParseServiceKey parses the service key formatted as name or name@@group.
+func ParseServiceKey(raw string) ServiceKey {
+	parts := strings.SplitN(raw, "@@", 2)
+	key := ServiceKey{ServiceName: parts[0]}
+	if len(parts) == 2 {
+		key.Group = parts[1]
+	}
+
+	return key
 }
 
 type ServiceInfo struct {
@@ -56,21 +78,145 @@ type ServiceInfo struct {
 	Port uint64
 
 	Metadata map[string]string
+
+	// Ephemeral determines whether the instances are registered as ephemeral instances.
+	Ephemeral bool
+
+	// NotReadyPolicy determines how to register the not ready addresses.
+	NotReadyPolicy NotReadyPolicy
+
+	// Weight is the default weight of the instances. Zero means the default weight.
+	Weight float64
+
+	// ClusterName is the nacos cluster of the instances. Empty means the default cluster.
+	ClusterName string
+
+	// AddressMode determines which addresses of service are registered.
+	AddressMode AddressMode
+
+	// MetadataPolicy determines how the metadata of instances are merged with the ones set outside
+	// syncer. Empty means overwrite.
+	MetadataPolicy MetadataPolicy
+
+	// Settings are the settings of nacos service itself. Nil means that they are not managed by syncer.
+	Settings *ServiceSettings
+
+	// AllowMassDeregistration bypasses the deregistration guard, which is the manual override of it.
+	AllowMassDeregistration bool
+
+	// Source is the k8s object which the instances come from, formatted as kind/namespace/name. The
+	// instances of a nacos service registered from several sources are kept apart, so that the sources
+	// do not unregister the instances of each other.
+	Source string
+
+	// Identity is the identity metadata carried by all the instances of service. The persistent
+	// instances with it in nacos are owned by syncer, even if they were registered before restart.
+	// Nil means that they can not be told apart from the ones registered outside syncer.
+	Identity map[string]string
+}
+
+// The kinds of k8s objects which the instances come from.
+const (
+	SourceKindService   = "Service"
+	SourceKindIngress   = "Ingress"
+	SourceKindHTTPRoute = "HTTPRoute"
+
+	// SourceKindPod is the source of the instances of all pods synced without k8s service.
+	SourceKindPod = "Pod"
+)
+
+// SourceOf returns the source of the instances which come from the k8s object of the kind.
+func SourceOf(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// ServiceSettings are the settings of nacos service itself rather than its instances. The nil ones
+// are left as they are.
+type ServiceSettings struct {
+	Metadata map[string]string
+
+	ProtectThreshold *float64
+
+	// Selector is the label expression which selects the instances for consumers, and empty means none.
+	Selector *string
+}
+
+// InstanceInfo is the information of a single instance, which is extracted from pod.
+type InstanceInfo struct {
+	Weight float64
+
+	Metadata map[string]string
 }
 
 type NacosClient interface {
-	RegisterService(ServiceInfo, []Address)
+	// RegisterService registers the addresses of service, and unregisters the instances which are
+	// absent in them. It returns DeregistrationRefusedError if the deregistration is refused by guard,
+	// and the instances refused to be unregistered are kept.
+	RegisterService(ServiceInfo, []Address) error
 
-	UnregisterService(ServiceInfo)
+	// UnregisterService unregisters the instances of service registered from its source. It returns
+	// DeregistrationRefusedError if the deregistration is refused by guard, and the instances are kept.
+	UnregisterService(ServiceInfo) error
 
 	RegisterServiceInstances(serviceInfo ServiceInfo, addresses []Address)
 
 	UnregisterServiceInstances(serviceInfo ServiceInfo, addresses []Address)
+
+	// Subscribe watches the instances of the service, and the callback is invoked when they change.
+	Subscribe(serviceKey ServiceKey, callback func()) error
+
+	Unsubscribe(serviceKey ServiceKey)
+
+	// SelectAllInstances returns all the instances of the service, including the unhealthy and disabled ones.
+	SelectAllInstances(serviceKey ServiceKey) ([]Address, error)
+
+	// ListServices returns the names of all services in the group.
+	ListServices(group string) ([]string, error)
+
+	// UpdateServiceSettings applies the settings of nacos service if they are changed.
+	UpdateServiceSettings(serviceInfo ServiceInfo)
+
+	// ReconcileServiceSettings applies the settings of registered services again, so that the
+	// changes made outside syncer are reverted. It returns when the timeout expires or nacos fails,
+	// and the next call continues with the services left.
+	ReconcileServiceSettings(timeout time.Duration)
+
+	// SetDeregistrationGuard sets the guard which refuses the mass deregistrations of instances.
+	SetDeregistrationGuard(guard *DeregistrationGuard)
+
+	// OwnedPersistentInstances returns the persistent instances registered by syncer with the cluster
+	// id, which are read from the nacos namespaces used by syncer and keyed by service.
+	OwnedPersistentInstances(clusterID string) (map[ServiceKey][]Address, error)
+
+	// UnregisterOrphanedInstances unregisters the persistent instances whose sources no longer exist.
+	// It returns DeregistrationRefusedError if the deregistration is refused by guard.
+	UnregisterOrphanedInstances(serviceKey ServiceKey, addresses []Address) error
 }
 
 type nacosClient struct {
-	client      naming_client.INamingClient
-	servicesMap map[ServiceKey][]Address
+	options NacosOptions
+
+	// clients are the naming clients of nacos namespaces, and the one of syncer is keyed by empty string.
+	clients map[string]naming_client.INamingClient
+
+	// servicesMap are the registered addresses of services keyed by their sources.
+	servicesMap map[ServiceKey]map[string][]Address
+
+	subscriptions map[ServiceKey]*vo.SubscribeParam
+
+	// openAPI manages the settings of nacos services, which are lacked in the sdk.
+	openAPI *nacosOpenAPI
+
+	// serviceSettings are the desired settings of the registered services, and appliedSettings are
+	// the ones applied successfully.
+	serviceSettings map[ServiceKey]ServiceSettings
+	appliedSettings map[ServiceKey]ServiceSettings
+
+	// pendingSettings are the services left to be reconciled in the current round.
+	pendingSettings []ServiceKey
+
+	// guard refuses the mass deregistrations of instances, and nil means no guard.
+	guard *DeregistrationGuard
 }
 
 func NewNacosClient(options NacosOptions) (NacosClient, error) {
@@ -81,41 +227,335 @@ func NewNacosClient(options NacosOptions) (NacosClient, error) {
 	}
 
 	return &nacosClient{
-		client:      client,
-		servicesMap: make(map[ServiceKey][]Address),
+		options:       options,
+		clients:       map[string]naming_client.INamingClient{"": client},
+		servicesMap:   make(map[ServiceKey]map[string][]Address),
+		subscriptions: make(map[ServiceKey]*vo.SubscribeParam),
+
+		openAPI:         newNacosOpenAPI(options),
+		serviceSettings: make(map[ServiceKey]ServiceSettings),
+		appliedSettings: make(map[ServiceKey]ServiceSettings),
 	}, nil
 }
 
-func (c *nacosClient) RegisterService(serviceInfo ServiceInfo, addresses []Address) {
-	old := c.servicesMap[serviceInfo.ServiceKey]
-	added, deleted := diffAddresses(old, addresses)
-	logger.Infof("Register service (%s@@%s), added %d, deleted %d.",
-		serviceInfo.ServiceName, serviceInfo.Group, len(added), len(deleted))
+// clientOf returns the naming client of the nacos namespace, which is created on demand.
+func (c *nacosClient) clientOf(namespace string) (naming_client.INamingClient, error) {
+	if namespace == c.options.Namespace {
+		namespace = ""
+	}
+	if client, exist := c.clients[namespace]; exist {
+		return client, nil
+	}
+
+	options := c.options
+	options.Namespace = namespace
+	client, err := clients.NewNamingClient(ConvertToNacosClientParam(options))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Create naming client of nacos namespace %s.", namespace)
+	c.clients[namespace] = client
+	return client, nil
+}
+
+func (c *nacosClient) RegisterService(serviceInfo ServiceInfo, addresses []Address) error {
+	old, registered := c.servicesMap[serviceInfo.ServiceKey][serviceInfo.Source]
+	if !registered && !serviceInfo.Ephemeral && serviceInfo.Identity != nil {
+		// The persistent instances are never expired, so the ones registered before restart are
+		// taken over, and the ones not desired any more are unregistered.
+		old = c.ownedInstances(serviceInfo)
+	}
+	addresses = filterDrainingAddresses(old, addresses)
+	added, updated, deleted := diffAddresses(old, addresses)
+	deleted = c.excludeOtherSources(serviceInfo, deleted)
+	logger.Infof("Register service (%s@@%s) from %s, added %d, updated %d, deleted %d.",
+		serviceInfo.ServiceName, serviceInfo.Group, serviceInfo.Source, len(added), len(updated), len(deleted))
+
+	var err error
+	if !serviceInfo.AllowMassDeregistration {
+		err = c.guard.Check(serviceInfo.ServiceKey, len(deleted), c.serviceInstances(serviceInfo.ServiceKey),
+			c.registeredInstances())
+	}
 
+	// The deleted instances are unregistered first, because the beat of ephemeral instance is keyed
+	// without cluster, and unregistering the one moved to another cluster stops the beat of the new one.
+	if err != nil {
+		// The instances refused to be unregistered are kept, so that they are checked again
+		// in the next registration.
+		logger.Warnf("Unregister instances of service (%s@@%s) fail, err %v.",
+			serviceInfo.ServiceName, serviceInfo.Group, err)
+		addresses = append(addresses, deleted...)
+	} else {
+		c.UnregisterServiceInstances(serviceInfo, deleted)
+		c.guard.Record(len(deleted))
+		if serviceInfo.Ephemeral {
+			// The instances moved to another cluster while the old ones were kept by guard are
+			// registered again to restore their beats.
+			updated = append(updated, beatSharedAddresses(deleted, addresses, append(added, updated...))...)
+		}
+	}
 	c.RegisterServiceInstances(serviceInfo, added)
-	c.UnregisterServiceInstances(serviceInfo, deleted)
+	c.RegisterServiceInstances(serviceInfo, updated)
+
+	if c.servicesMap[serviceInfo.ServiceKey] == nil {
+		c.servicesMap[serviceInfo.ServiceKey] = make(map[string][]Address)
+	}
+	c.servicesMap[serviceInfo.ServiceKey][serviceInfo.Source] = addresses
 
-	c.servicesMap[serviceInfo.ServiceKey] = addresses
+	c.UpdateServiceSettings(serviceInfo)
+	return err
 }
 
-func (c *nacosClient) UnregisterService(serviceInfo ServiceInfo) {
-	logger.Infof("Unregister service (%s@@%s).", serviceInfo.ServiceName, serviceInfo.Group)
-	c.UnregisterServiceInstances(serviceInfo, c.servicesMap[serviceInfo.ServiceKey])
+// excludeOtherSources removes the addresses which are registered from the other sources of service,
+// because they are still desired.
+func (c *nacosClient) excludeOtherSources(serviceInfo ServiceInfo, addresses []Address) []Address {
+	others := make(map[addressKey]struct{})
+	for source, registered := range c.servicesMap[serviceInfo.ServiceKey] {
+		if source == serviceInfo.Source {
+			continue
+		}
+		for _, address := range registered {
+			others[address.key()] = struct{}{}
+		}
+	}
+	if len(others) == 0 {
+		return addresses
+	}
+
+	var excluded []Address
+	for _, address := range addresses {
+		if _, exist := others[address.key()]; !exist {
+			excluded = append(excluded, address)
+		}
+	}
+	return excluded
+}
+
+// serviceInstances returns the count of instances of the service registered from all sources.
+func (c *nacosClient) serviceInstances(serviceKey ServiceKey) int {
+	count := 0
+	for _, addresses := range c.servicesMap[serviceKey] {
+		count += len(addresses)
+	}
+	return count
+}
+
+// ownedInstances returns the instances of service in nacos which are owned by syncer.
+func (c *nacosClient) ownedInstances(serviceInfo ServiceInfo) []Address {
+	addresses, err := c.queryInstances(serviceInfo.ServiceKey)
+	if err != nil {
+		logger.Errorf("Select instances of service (%s@@%s) fail, err %v.",
+			serviceInfo.ServiceName, serviceInfo.Group, err)
+		return nil
+	}
+
+	var owned []Address
+	for _, address := range addresses {
+		if !IsOwnedInstance(address, serviceInfo.Identity) {
+			continue
+		}
+		// The instances registered without cluster belong to the default cluster of nacos.
+		if address.ClusterName == defaultNacosCluster {
+			address.ClusterName = ""
+		}
+		owned = append(owned, address)
+	}
+
+	if len(owned) > 0 {
+		logger.Infof("Take over %d instances of service (%s@@%s) registered before.",
+			len(owned), serviceInfo.ServiceName, serviceInfo.Group)
+	}
+	return owned
+}
+
+// registeredInstances returns the count of instances of all services registered by syncer.
+func (c *nacosClient) registeredInstances() int {
+	count := 0
+	for serviceKey := range c.servicesMap {
+		count += c.serviceInstances(serviceKey)
+	}
+	return count
+}
+
+func (c *nacosClient) SetDeregistrationGuard(guard *DeregistrationGuard) {
+	c.guard = guard
+}
+
+func (c *nacosClient) OwnedPersistentInstances(clusterID string) (map[ServiceKey][]Address, error) {
+	identity := map[string]string{MetadataKubeCluster: clusterID}
+	instances := make(map[ServiceKey][]Address)
+	// The nacos namespaces which are not used by syncer since restart are not listed.
+	for namespace := range c.clients {
+		serviceKeys, err := c.openAPI.listServices(c.namespaceIDOf(ServiceKey{Namespace: namespace}))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, serviceKey := range serviceKeys {
+			serviceKey.Namespace = namespace
+			details, err := c.openAPI.listInstanceDetails(serviceKey, c.namespaceIDOf(serviceKey))
+			if err != nil {
+				return nil, err
+			}
+			for _, instance := range details {
+				address := instance.address()
+				if instance.Ephemeral || !IsOwnedInstance(address, identity) {
+					continue
+				}
+				// The instances registered without cluster belong to the default cluster of nacos.
+				if address.ClusterName == defaultNacosCluster {
+					address.ClusterName = ""
+				}
+				instances[serviceKey] = append(instances[serviceKey], address)
+			}
+		}
+	}
+
+	return instances, nil
+}
+
+func (c *nacosClient) UnregisterOrphanedInstances(serviceKey ServiceKey, addresses []Address) error {
+	logger.Infof("Unregister %d orphaned instances of service (%s@@%s).",
+		len(addresses), serviceKey.ServiceName, serviceKey.Group)
+	if err := c.guard.CheckUnregistration(serviceKey, len(addresses), c.registeredInstances()); err != nil {
+		logger.Warnf("Unregister orphaned instances of service (%s@@%s) fail, err %v.",
+			serviceKey.ServiceName, serviceKey.Group, err)
+		return err
+	}
+
+	c.UnregisterServiceInstances(ServiceInfo{ServiceKey: serviceKey}, addresses)
+	c.guard.Record(len(addresses))
+	return nil
+}
+
+func (c *nacosClient) UnregisterService(serviceInfo ServiceInfo) error {
+	logger.Infof("Unregister service (%s@@%s) from %s.", serviceInfo.ServiceName, serviceInfo.Group, serviceInfo.Source)
+	sources := c.servicesMap[serviceInfo.ServiceKey]
+	deleted := c.excludeOtherSources(serviceInfo, sources[serviceInfo.Source])
+	if !serviceInfo.AllowMassDeregistration {
+		if err := c.guard.CheckUnregistration(serviceInfo.ServiceKey, len(deleted), c.registeredInstances()); err != nil {
+			logger.Warnf("Unregister service (%s@@%s) fail, err %v.", serviceInfo.ServiceName, serviceInfo.Group, err)
+			return err
+		}
+	}
+
+	c.UnregisterServiceInstances(serviceInfo, deleted)
+	c.guard.Record(len(deleted))
+	delete(sources, serviceInfo.Source)
+	if len(sources) > 0 {
+		// The service is still registered from the other sources.
+		return nil
+	}
+
 	delete(c.servicesMap, serviceInfo.ServiceKey)
+	delete(c.serviceSettings, serviceInfo.ServiceKey)
+	delete(c.appliedSettings, serviceInfo.ServiceKey)
+	c.guard.Forget(serviceInfo.ServiceKey)
+	return nil
+}
+
+func (c *nacosClient) UpdateServiceSettings(serviceInfo ServiceInfo) {
+	if serviceInfo.Settings == nil {
+		delete(c.serviceSettings, serviceInfo.ServiceKey)
+		delete(c.appliedSettings, serviceInfo.ServiceKey)
+		return
+	}
+
+	c.serviceSettings[serviceInfo.ServiceKey] = *serviceInfo.Settings
+	applied, ok := c.appliedSettings[serviceInfo.ServiceKey]
+	if ok && reflect.DeepEqual(applied, *serviceInfo.Settings) {
+		return
+	}
+	c.applyServiceSettings(serviceInfo.ServiceKey, *serviceInfo.Settings)
+}
+
+func (c *nacosClient) ReconcileServiceSettings(timeout time.Duration) {
+	if len(c.pendingSettings) == 0 {
+		for serviceKey := range c.serviceSettings {
+			c.pendingSettings = append(c.pendingSettings, serviceKey)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for len(c.pendingSettings) > 0 && time.Now().Before(deadline) {
+		serviceKey := c.pendingSettings[0]
+		c.pendingSettings = c.pendingSettings[1:]
+		settings, ok := c.serviceSettings[serviceKey]
+		if !ok {
+			// The service is unregistered after the round started.
+			continue
+		}
+		// The others are likely to fail as well, so they are left to the next round.
+		if err := c.applyServiceSettings(serviceKey, settings); err != nil {
+			return
+		}
+	}
+}
+
+// namespaceIDOf returns the nacos namespace of the service, and the one of syncer is denoted by empty.
+func (c *nacosClient) namespaceIDOf(serviceKey ServiceKey) string {
+	if serviceKey.Namespace == "" {
+		return c.options.Namespace
+	}
+	return serviceKey.Namespace
+}
+
+func (c *nacosClient) applyServiceSettings(serviceKey ServiceKey, settings ServiceSettings) error {
+	namespaceID := c.namespaceIDOf(serviceKey)
+
+	if err := c.openAPI.applyServiceSettings(serviceKey, namespaceID, settings); err != nil {
+		logger.Errorf("Apply settings of service (%s@@%s) fail, err %v.", serviceKey.ServiceName, serviceKey.Group, err)
+		delete(c.appliedSettings, serviceKey)
+		return err
+	}
+	c.appliedSettings[serviceKey] = settings
+	return nil
 }
 
 func (c *nacosClient) RegisterServiceInstances(serviceInfo ServiceInfo, addresses []Address) {
+	if len(addresses) == 0 {
+		return
+	}
+
+	client, err := c.clientOf(serviceInfo.Namespace)
+	if err != nil {
+		logger.Errorf("Create naming client of nacos namespace %s fail, err %v.", serviceInfo.Namespace, err)
+		return
+	}
+
+	// The metadata set outside syncer are read from nacos unless they are overwritten.
+	var existing map[addressKey]map[string]string
+	if serviceInfo.MetadataPolicy != "" && serviceInfo.MetadataPolicy != MetadataOverwrite {
+		existing, err = c.existingMetadata(serviceInfo.ServiceKey)
+		if err != nil {
+			logger.Errorf("Select instances of service (%s@@%s) fail, err %v.",
+				serviceInfo.ServiceName, serviceInfo.Group, err)
+			return
+		}
+	}
+
 	for _, address := range addresses {
-		if _, err := c.client.RegisterInstance(vo.RegisterInstanceParam{
+		metadata := MergeMetadata(serviceInfo.Metadata, address.Metadata)
+		if existing != nil {
+			key := address.key()
+			if key.ClusterName == defaultNacosCluster {
+				key.ClusterName = ""
+			}
+			metadata = MergeForeignMetadata(serviceInfo.MetadataPolicy, existing[key], metadata)
+		}
+
+		if _, err := client.RegisterInstance(vo.RegisterInstanceParam{
 			Ip:          address.IP,
 			Port:        address.Port,
-			Weight:      DefaultNacosEndpointWeight,
-			Enable:      true,
-			Healthy:     true,
-			Metadata:    serviceInfo.Metadata,
+			Weight:      address.Weight,
+			Enable:      address.Enable,
+			Healthy:     address.Healthy,
+			Metadata:    metadata,
+			ClusterName: address.ClusterName,
 			ServiceName: serviceInfo.ServiceName,
 			GroupName:   serviceInfo.Group,
-			Ephemeral:   true,
+			Ephemeral:   serviceInfo.Ephemeral,
 		}); err != nil {
 			logger.Errorf("Register instance (%s:%d) with service (%s@@%s) fail, err %v.",
 				address.IP, address.Port, serviceInfo.ServiceName, serviceInfo.Group, err)
@@ -123,14 +563,50 @@ func (c *nacosClient) RegisterServiceInstances(serviceInfo ServiceInfo, addresse
 	}
 }
 
+// queryInstances reads the instances of the service from nacos servers instead of the cache of the sdk,
+// which may be stale before the service is subscribed.
+func (c *nacosClient) queryInstances(serviceKey ServiceKey) ([]Address, error) {
+	return c.openAPI.listInstances(serviceKey, c.namespaceIDOf(serviceKey))
+}
+
+// existingMetadata returns the metadata of the instances of service in nacos.
+func (c *nacosClient) existingMetadata(serviceKey ServiceKey) (map[addressKey]map[string]string, error) {
+	addresses, err := c.queryInstances(serviceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[addressKey]map[string]string, len(addresses))
+	for _, address := range addresses {
+		key := address.key()
+		// The instances registered without cluster belong to the default cluster of nacos.
+		if key.ClusterName == defaultNacosCluster {
+			key.ClusterName = ""
+		}
+		existing[key] = address.Metadata
+	}
+	return existing, nil
+}
+
 func (c *nacosClient) UnregisterServiceInstances(serviceInfo ServiceInfo, addresses []Address) {
+	if len(addresses) == 0 {
+		return
+	}
+
+	client, err := c.clientOf(serviceInfo.Namespace)
+	if err != nil {
+		logger.Errorf("Create naming client of nacos namespace %s fail, err %v.", serviceInfo.Namespace, err)
+		return
+	}
+
 	for _, address := range addresses {
-		if _, err := c.client.DeregisterInstance(vo.DeregisterInstanceParam{
+		if _, err := client.DeregisterInstance(vo.DeregisterInstanceParam{
 			Ip:          address.IP,
 			Port:        address.Port,
+			Cluster:     address.ClusterName,
 			ServiceName: serviceInfo.ServiceName,
 			GroupName:   serviceInfo.Group,
-			Ephemeral:   true,
+			Ephemeral:   serviceInfo.Ephemeral,
 		}); err != nil {
 			logger.Errorf("Unregister instance (%s:%d) with service (%s@@%s) fail, err %v.",
 				address.IP, address.Port, serviceInfo.ServiceName, serviceInfo.Group, err)
@@ -138,52 +614,306 @@ func (c *nacosClient) UnregisterServiceInstances(serviceInfo ServiceInfo, addres
 	}
 }
 
+func (c *nacosClient) Subscribe(serviceKey ServiceKey, callback func()) error {
+	if _, exist := c.subscriptions[serviceKey]; exist {
+		return nil
+	}
+
+	param := &vo.SubscribeParam{
+		ServiceName: serviceKey.ServiceName,
+		GroupName:   serviceKey.Group,
+		SubscribeCallback: func(_ []nacosmodel.SubscribeService, err error) {
+			if err != nil {
+				logger.Errorf("Subscribe service (%s@@%s) fail, err %v.", serviceKey.ServiceName, serviceKey.Group, err)
+				return
+			}
+			callback()
+		},
+	}
+	client, err := c.clientOf(serviceKey.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := client.Subscribe(param); err != nil {
+		return err
+	}
+
+	logger.Infof("Subscribe service (%s@@%s).", serviceKey.ServiceName, serviceKey.Group)
+	c.subscriptions[serviceKey] = param
+	return nil
+}
+
+func (c *nacosClient) Unsubscribe(serviceKey ServiceKey) {
+	param, exist := c.subscriptions[serviceKey]
+	if !exist {
+		return
+	}
+
+	logger.Infof("Unsubscribe service (%s@@%s).", serviceKey.ServiceName, serviceKey.Group)
+	client, err := c.clientOf(serviceKey.Namespace)
+	if err == nil {
+		err = client.Unsubscribe(param)
+	}
+	if err != nil {
+		logger.Errorf("Unsubscribe service (%s@@%s) fail, err %v.", serviceKey.ServiceName, serviceKey.Group, err)
+	}
+	delete(c.subscriptions, serviceKey)
+}
+
+func (c *nacosClient) SelectAllInstances(serviceKey ServiceKey) ([]Address, error) {
+	client, err := c.clientOf(serviceKey.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := client.SelectAllInstances(vo.SelectAllInstancesParam{
+		ServiceName: serviceKey.ServiceName,
+		GroupName:   serviceKey.Group,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]Address, 0, len(instances))
+	for _, instance := range instances {
+		addresses = append(addresses, Address{
+			IP:          instance.Ip,
+			Port:        instance.Port,
+			Healthy:     instance.Healthy,
+			Enable:      instance.Enable,
+			Weight:      instance.Weight,
+			ClusterName: instance.ClusterName,
+			Metadata:    instance.Metadata,
+		})
+	}
+
+	return addresses, nil
+}
+
+func (c *nacosClient) ListServices(group string) ([]string, error) {
+	var services []string
+	for pageNo := uint32(1); ; pageNo++ {
+		serviceList, err := c.clients[""].GetAllServicesInfo(vo.GetAllServiceInfoParam{
+			GroupName: group,
+			PageNo:    pageNo,
+			PageSize:  listServicesPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		services = append(services, serviceList.Doms...)
+		if len(serviceList.Doms) < listServicesPageSize || int64(len(services)) >= serviceList.Count {
+			return services, nil
+		}
+	}
+}
+
 type Address struct {
 	IP   string `json:"ip"`
 	Port uint64 `json:"port"`
+
+	// Healthy and Enable reflect the readiness of the endpoint in k8s
+	// according to the not ready policy.
+	Healthy bool `json:"healthy"`
+	Enable  bool `json:"enable"`
+
+	Weight float64 `json:"weight"`
+
+	// Draining is true if the address belongs to a terminating pod.
+	Draining bool `json:"draining"`
+
+	ClusterName string `json:"clusterName"`
+
+	// NodeName is the node where the pod of address is running.
+	NodeName string `json:"nodeName"`
+
+	// PodName is the name of pod which the address belongs to.
+	PodName string `json:"podName"`
+
+	// Metadata is the metadata of the single instance, which takes precedence
+	// over the metadata of service.
+	Metadata map[string]string `json:"metadata"`
+}
+
+// addressKey identifies an instance in nacos.
+type addressKey struct {
+	IP          string
+	Port        uint64
+	ClusterName string
+}
+
+func (a Address) key() addressKey {
+	return addressKey{
+		IP:          a.IP,
+		Port:        a.Port,
+		ClusterName: a.ClusterName,
+	}
+}
+
+// filterDrainingAddresses removes the draining addresses which have not been registered,
+// because only the registered instances need to be drained.
+func filterDrainingAddresses(old, curr []Address) []Address {
+	registered := make(map[addressKey]struct{}, len(old))
+	for _, s := range old {
+		registered[s.key()] = struct{}{}
+	}
+
+	var filtered []Address
+	for _, s := range curr {
+		if _, exist := registered[s.key()]; s.Draining && !exist {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+
+	return filtered
+}
+
+// beatSharedAddresses returns the addresses which share the ip and port with the deleted ones, except
+// the changed ones. The beat of ephemeral instance is keyed without cluster, so it is stopped by
+// unregistering the deleted one.
+func beatSharedAddresses(deleted, addresses, changed []Address) []Address {
+	type ipPort struct {
+		IP   string
+		Port uint64
+	}
+
+	deletedSet := make(map[ipPort]struct{}, len(deleted))
+	for _, s := range deleted {
+		deletedSet[ipPort{IP: s.IP, Port: s.Port}] = struct{}{}
+	}
+	changedSet := make(map[addressKey]struct{}, len(changed))
+	for _, s := range changed {
+		changedSet[s.key()] = struct{}{}
+	}
+
+	var shared []Address
+	for _, s := range addresses {
+		if _, exist := deletedSet[ipPort{IP: s.IP, Port: s.Port}]; !exist {
+			continue
+		}
+		if _, exist := changedSet[s.key()]; !exist {
+			shared = append(shared, s)
+		}
+	}
+
+	return shared
 }
 
-func diffAddresses(old, curr []Address) ([]Address, []Address) {
-	var added, deleted []Address
-	oldAddressesSet := make(map[Address]struct{}, len(old))
-	newAddressesSet := make(map[Address]struct{}, len(curr))
+// diffAddresses returns the addresses which should be added, updated and deleted.
+// The updated addresses are the ones whose state changed with the same ip and port.
+func diffAddresses(old, curr []Address) ([]Address, []Address, []Address) {
+	var added, updated, deleted []Address
+	oldAddressesSet := make(map[addressKey]Address, len(old))
+	newAddressesSet := make(map[addressKey]Address, len(curr))
 
 	for _, s := range old {
-		oldAddressesSet[s] = struct{}{}
+		oldAddressesSet[s.key()] = s
 	}
 	for _, s := range curr {
-		newAddressesSet[s] = struct{}{}
+		newAddressesSet[s.key()] = s
 	}
 
-	for oldAddress := range oldAddressesSet {
-		if _, exist := newAddressesSet[oldAddress]; !exist {
+	for key, oldAddress := range oldAddressesSet {
+		if _, exist := newAddressesSet[key]; !exist {
 			deleted = append(deleted, oldAddress)
 		}
 	}
 
-	for newAddress := range newAddressesSet {
-		if _, exist := oldAddressesSet[newAddress]; !exist {
+	for key, newAddress := range newAddressesSet {
+		oldAddress, exist := oldAddressesSet[key]
+		if !exist {
 			added = append(added, newAddress)
+		} else if !reflect.DeepEqual(oldAddress, newAddress) {
+			updated = append(updated, newAddress)
 		}
 	}
 
-	return added, deleted
+	return added, updated, deleted
+}
+
+// InstanceWeight returns the default weight of the instances of service.
+func (s ServiceInfo) InstanceWeight() float64 {
+	if s.Weight > 0 {
+		return s.Weight
+	}
+
+	return DefaultNacosEndpointWeight
 }
 
-func ConvertToAddresses(realPort uint64, endpoints *v1.Endpoints) []Address {
+func ConvertToAddresses(serviceInfo ServiceInfo, endpoints *v1.Endpoints) []Address {
 	var addresses []Address
 	for _, subset := range endpoints.Subsets {
-		for _, address := range subset.Addresses {
-			for _, port := range subset.Ports {
-				if port.Port == int32(realPort) {
-					addresses = append(addresses, Address{
-						IP:   address.IP,
-						Port: realPort,
-					})
-				}
+		for _, port := range subset.Ports {
+			if port.Port != int32(serviceInfo.Port) {
+				continue
+			}
+
+			for _, address := range subset.Addresses {
+				addresses = append(addresses, Address{
+					IP:          address.IP,
+					Port:        serviceInfo.Port,
+					Healthy:     true,
+					Enable:      true,
+					Weight:      serviceInfo.InstanceWeight(),
+					ClusterName: serviceInfo.ClusterName,
+					NodeName:    nodeNameOf(address),
+					PodName:     podNameOf(address),
+				})
+			}
+
+			if serviceInfo.NotReadyPolicy == NotReadyOmit {
+				continue
+			}
+
+			for _, address := range subset.NotReadyAddresses {
+				addresses = append(addresses, Address{
+					IP:          address.IP,
+					Port:        serviceInfo.Port,
+					Healthy:     serviceInfo.NotReadyPolicy != NotReadyUnhealthy,
+					Enable:      serviceInfo.NotReadyPolicy != NotReadyDisabled,
+					Weight:      serviceInfo.InstanceWeight(),
+					ClusterName: serviceInfo.ClusterName,
+					NodeName:    nodeNameOf(address),
+					PodName:     podNameOf(address),
+				})
 			}
 		}
 	}
 
 	return addresses
 }
+
+func podNameOf(address v1.EndpointAddress) string {
+	if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+		return ""
+	}
+
+	return address.TargetRef.Name
+}
+
+func nodeNameOf(address v1.EndpointAddress) string {
+	if address.NodeName == nil {
+		return ""
+	}
+
+	return *address.NodeName
+}
+
+// MergeMetadata merges the metadata of instance into the one of service.
+func MergeMetadata(serviceMeta, instanceMeta map[string]string) map[string]string {
+	if len(instanceMeta) == 0 {
+		return serviceMeta
+	}
+
+	merged := make(map[string]string, len(serviceMeta)+len(instanceMeta))
+	for key, value := range serviceMeta {
+		merged[key] = value
+	}
+	for key, value := range instanceMeta {
+		merged[key] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sortAddresses(addresses []Address) []Address {
+	sort.Slice(addresses, func(i, j int) bool {
+		if addresses[i].IP != addresses[j].IP {
+			return addresses[i].IP < addresses[j].IP
+		}
+		if addresses[i].Port != addresses[j].Port {
+			return addresses[i].Port < addresses[j].Port
+		}
+		return addresses[i].ClusterName < addresses[j].ClusterName
+	})
+	return addresses
+}
+
+func TestDiffAddresses(t *testing.T) {
+	a := Address{IP: "10.0.0.1", Port: 8080, Healthy: true, Enable: true, Weight: 100}
+	b := Address{IP: "10.0.0.2", Port: 8080, Healthy: true, Enable: true, Weight: 100}
+
+	inZone := func(address Address, cluster string) Address {
+		address.ClusterName = cluster
+		return address
+	}
+	unhealthy := func(address Address) Address {
+		address.Healthy = false
+		return address
+	}
+
+	cases := []struct {
+		name    string
+		old     []Address
+		curr    []Address
+		added   []Address
+		updated []Address
+		deleted []Address
+	}{
+		{
+			name:  "add",
+			old:   []Address{a},
+			curr:  []Address{a, b},
+			added: []Address{b},
+		},
+		{
+			name:    "delete",
+			old:     []Address{a, b},
+			curr:    []Address{a},
+			deleted: []Address{b},
+		},
+		{
+			name:    "update state",
+			old:     []Address{a, b},
+			curr:    []Address{a, unhealthy(b)},
+			updated: []Address{unhealthy(b)},
+		},
+		{
+			name:    "move to cluster",
+			old:     []Address{a, b},
+			curr:    []Address{a, inZone(b, "zone-a")},
+			added:   []Address{inZone(b, "zone-a")},
+			deleted: []Address{b},
+		},
+		{
+			name:    "move between clusters",
+			old:     []Address{inZone(a, "zone-a"), inZone(b, "zone-a")},
+			curr:    []Address{inZone(a, "zone-b"), inZone(b, "zone-a")},
+			added:   []Address{inZone(a, "zone-b")},
+			deleted: []Address{inZone(a, "zone-a")},
+		},
+		{
+			name: "unchanged",
+			old:  []Address{inZone(a, "zone-a"), b},
+			curr: []Address{inZone(a, "zone-a"), b},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			added, updated, deleted := diffAddresses(c.old, c.curr)
+			if !reflect.DeepEqual(sortAddresses(added), c.added) {
+				t.Errorf("added %v, want %v", added, c.added)
+			}
+			if !reflect.DeepEqual(sortAddresses(updated), c.updated) {
+				t.Errorf("updated %v, want %v", updated, c.updated)
+			}
+			if !reflect.DeepEqual(sortAddresses(deleted), c.deleted) {
+				t.Errorf("deleted %v, want %v", deleted, c.deleted)
+			}
+		})
+	}
+}
+
+func TestBeatSharedAddresses(t *testing.T) {
+	a := Address{IP: "10.0.0.1", Port: 8080, ClusterName: "zone-a"}
+	movedA := Address{IP: "10.0.0.1", Port: 8080, ClusterName: "zone-b"}
+	otherPort := Address{IP: "10.0.0.1", Port: 9090, ClusterName: "zone-b"}
+	b := Address{IP: "10.0.0.2", Port: 8080, ClusterName: "zone-a"}
+
+	cases := []struct {
+		name      string
+		deleted   []Address
+		addresses []Address
+		changed   []Address
+		want      []Address
+	}{
+		{
+			name:      "moved before",
+			deleted:   []Address{a},
+			addresses: []Address{movedA, otherPort, b},
+			want:      []Address{movedA},
+		},
+		{
+			name:      "moved now",
+			deleted:   []Address{a},
+			addresses: []Address{movedA, b},
+			changed:   []Address{movedA},
+		},
+		{
+			name:      "deleted only",
+			deleted:   []Address{b},
+			addresses: []Address{a},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := beatSharedAddresses(c.deleted, c.addresses, c.changed); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
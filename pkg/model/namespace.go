@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// inheritedAnnotations are the annotations of namespace which the services in it inherit
+// unless they override them.
+var inheritedAnnotations = []string{
+	annotationServiceSync,
+	annotationServiceGroup,
+	annotationNacosNamespace,
+	annotationInstanceMeta,
+	annotationMetadataPolicy,
+}
+
+// NamespaceDefaults returns the annotations of namespace inherited by the services in it, which are
+// keyed by the names of annotations.
+func NamespaceDefaults(namespace *v1.Namespace) map[string]string {
+	if namespace == nil {
+		return nil
+	}
+
+	var defaults map[string]string
+	for _, name := range inheritedAnnotations {
+		if value, ok := lookupAnnotation(namespace.Annotations, name); ok {
+			if defaults == nil {
+				defaults = make(map[string]string)
+			}
+			defaults[name] = value
+		}
+	}
+
+	return defaults
+}
+
+// InheritNamespaceDefaults returns the copy of service with the defaults of its namespace, and the
+// service itself if there are no defaults. The annotations of service take precedence, except that
+// the meta of service is merged into the default meta.
+func InheritNamespaceDefaults(svc *v1.Service, defaults map[string]string) (*v1.Service, error) {
+	if len(defaults) == 0 {
+		return svc, nil
+	}
+
+	annotations := make(map[string]string, len(svc.Annotations)+len(defaults))
+	for key, value := range svc.Annotations {
+		annotations[key] = value
+	}
+	for name, value := range defaults {
+		if _, ok := lookupAnnotation(svc.Annotations, name); !ok {
+			annotations[AnnotationKey(name)] = value
+		}
+	}
+
+	if rawMeta, ok := defaults[annotationInstanceMeta]; ok {
+		var meta map[string]string
+		if err := json.Unmarshal([]byte(rawMeta), &meta); err != nil {
+			return nil, fmt.Errorf("invalid default meta of namespace %s, err %v", svc.Namespace, err)
+		}
+
+		// The invalid meta of service is kept to be reported when generating the service info.
+		rawServiceMeta := annotationOf(svc.Annotations, annotationInstanceMeta)
+		var serviceMeta map[string]string
+		if rawServiceMeta == "" || json.Unmarshal([]byte(rawServiceMeta), &serviceMeta) == nil {
+			merged, err := json.Marshal(MergeMetadata(meta, serviceMeta))
+			if err != nil {
+				return nil, err
+			}
+			annotations[AnnotationKey(annotationInstanceMeta)] = string(merged)
+		}
+	}
+
+	inherited := svc.DeepCopy()
+	inherited.Annotations = annotations
+	return inherited, nil
+}
@@ -0,0 +1,305 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/common/constant"
+)
+
+const (
+	nacosServicePath      = "/nacos/v1/ns/service"
+	nacosInstanceListPath = "/nacos/v1/ns/instance/list"
+	nacosCatalogPath      = "/nacos/v1/ns/catalog/services"
+
+	catalogPageSize = 100
+
+	selectorTypeNone  = "none"
+	selectorTypeLabel = "label"
+)
+
+// nacosOpenAPI calls the open api of nacos servers, which covers the features lacked in the sdk.
+type nacosOpenAPI struct {
+	servers []string
+
+	client *http.Client
+}
+
+func newNacosOpenAPI(options NacosOptions) *nacosOpenAPI {
+	var servers []string
+	for _, ip := range options.ServersIP {
+		servers = append(servers, fmt.Sprintf("%s:%d", ip, options.ServerPort))
+	}
+
+	return &nacosOpenAPI{
+		servers: servers,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// nacosSelector is the selector of nacos service, which selects the instances for the consumers.
+type nacosSelector struct {
+	Type string `json:"type"`
+
+	Expression string `json:"expression,omitempty"`
+}
+
+// nacosServiceDetail is the nacos service returned by the open api.
+type nacosServiceDetail struct {
+	ProtectThreshold float64 `json:"protectThreshold"`
+
+	Metadata map[string]string `json:"metadata"`
+
+	Selector nacosSelector `json:"selector"`
+}
+
+// nacosInstance is the instance returned by the open api.
+type nacosInstance struct {
+	IP          string            `json:"ip"`
+	Port        uint64            `json:"port"`
+	Healthy     bool              `json:"healthy"`
+	Enabled     bool              `json:"enabled"`
+	Weight      float64           `json:"weight"`
+	ClusterName string            `json:"clusterName"`
+	Ephemeral   bool              `json:"ephemeral"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+func (i nacosInstance) address() Address {
+	return Address{
+		IP:          i.IP,
+		Port:        i.Port,
+		Healthy:     i.Healthy,
+		Enable:      i.Enabled,
+		Weight:      i.Weight,
+		ClusterName: i.ClusterName,
+		Metadata:    i.Metadata,
+	}
+}
+
+// nacosInstanceList is the instance list of nacos service returned by the open api.
+type nacosInstanceList struct {
+	Hosts []nacosInstance `json:"hosts"`
+}
+
+// nacosCatalog is a page of the services of nacos namespace returned by the open api.
+type nacosCatalog struct {
+	Count int `json:"count"`
+
+	ServiceList []struct {
+		Name      string `json:"name"`
+		GroupName string `json:"groupName"`
+	} `json:"serviceList"`
+}
+
+// request sends the request to the servers in order until one of them responds.
+func (a *nacosOpenAPI) request(method, path string, params url.Values) (int, []byte, error) {
+	if len(a.servers) == 0 {
+		return 0, nil, fmt.Errorf("no nacos server specified")
+	}
+
+	var lastErr error
+	for _, server := range a.servers {
+		u := url.URL{Scheme: "http", Host: server, Path: path}
+		var body io.Reader
+		if method == http.MethodGet {
+			u.RawQuery = params.Encode()
+		} else {
+			body = strings.NewReader(params.Encode())
+		}
+
+		req, err := http.NewRequest(method, u.String(), body)
+		if err != nil {
+			return 0, nil, err
+		}
+		if method != http.MethodGet {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+
+		resp, err := a.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp.StatusCode, data, nil
+	}
+
+	return 0, nil, lastErr
+}
+
+func serviceParams(key ServiceKey, namespaceID string) url.Values {
+	group := key.Group
+	if group == "" {
+		group = constant.DEFAULT_GROUP
+	}
+
+	params := url.Values{}
+	params.Set("serviceName", key.ServiceName)
+	params.Set("groupName", group)
+	params.Set("namespaceId", namespaceID)
+	return params
+}
+
+// getService returns the nacos service, and returns false if it does not exist.
+func (a *nacosOpenAPI) getService(key ServiceKey, namespaceID string) (nacosServiceDetail, bool, error) {
+	code, data, err := a.request(http.MethodGet, nacosServicePath, serviceParams(key, namespaceID))
+	if err != nil {
+		return nacosServiceDetail{}, false, err
+	}
+	if code != http.StatusOK {
+		if strings.Contains(string(data), "not found") {
+			return nacosServiceDetail{}, false, nil
+		}
+		return nacosServiceDetail{}, false, fmt.Errorf("get service fail, code %d, body %s", code, data)
+	}
+
+	var detail nacosServiceDetail
+	if err := json.Unmarshal(data, &detail); err != nil {
+		return nacosServiceDetail{}, false, err
+	}
+	return detail, true, nil
+}
+
+// listInstances returns all the instances of the nacos service read from the servers, including the
+// unhealthy ones, which are not delayed by the cache of the sdk.
+func (a *nacosOpenAPI) listInstances(key ServiceKey, namespaceID string) ([]Address, error) {
+	instances, err := a.listInstanceDetails(key, namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]Address, 0, len(instances))
+	for _, instance := range instances {
+		addresses = append(addresses, instance.address())
+	}
+	return addresses, nil
+}
+
+func (a *nacosOpenAPI) listInstanceDetails(key ServiceKey, namespaceID string) ([]nacosInstance, error) {
+	params := serviceParams(key, namespaceID)
+	params.Set("healthyOnly", "false")
+	code, data, err := a.request(http.MethodGet, nacosInstanceListPath, params)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		if strings.Contains(string(data), "not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list instances fail, code %d, body %s", code, data)
+	}
+
+	var list nacosInstanceList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list.Hosts, nil
+}
+
+// listServices returns the keys of all the services in the nacos namespace, whose namespace is
+// left empty.
+func (a *nacosOpenAPI) listServices(namespaceID string) ([]ServiceKey, error) {
+	var keys []ServiceKey
+	for pageNo := 1; ; pageNo++ {
+		params := url.Values{}
+		params.Set("withInstances", "false")
+		params.Set("pageNo", strconv.Itoa(pageNo))
+		params.Set("pageSize", strconv.Itoa(catalogPageSize))
+		params.Set("namespaceId", namespaceID)
+		code, data, err := a.request(http.MethodGet, nacosCatalogPath, params)
+		if err != nil {
+			return nil, err
+		}
+		if code != http.StatusOK {
+			return nil, fmt.Errorf("list services fail, code %d, body %s", code, data)
+		}
+
+		var catalog nacosCatalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, err
+		}
+		for _, service := range catalog.ServiceList {
+			keys = append(keys, ServiceKey{ServiceName: service.Name, Group: service.GroupName})
+		}
+		if len(catalog.ServiceList) < catalogPageSize || len(keys) >= catalog.Count {
+			return keys, nil
+		}
+	}
+}
+
+// applyServiceSettings creates or updates the nacos service with the settings, and the settings not
+// specified are kept as they are. Nothing is changed if the service already has the settings.
+func (a *nacosOpenAPI) applyServiceSettings(key ServiceKey, namespaceID string, settings ServiceSettings) error {
+	detail, exist, err := a.getService(key, namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if detail.Selector.Type == "" {
+		detail.Selector.Type = selectorTypeNone
+	}
+
+	desired := detail
+	if settings.Metadata != nil {
+		desired.Metadata = settings.Metadata
+	}
+	if settings.ProtectThreshold != nil {
+		desired.ProtectThreshold = *settings.ProtectThreshold
+	}
+	if settings.Selector != nil {
+		desired.Selector = nacosSelector{Type: selectorTypeNone}
+		if *settings.Selector != "" {
+			desired.Selector = nacosSelector{Type: selectorTypeLabel, Expression: *settings.Selector}
+		}
+	}
+	if exist && metadataEqual(desired.Metadata, detail.Metadata) &&
+		desired.ProtectThreshold == detail.ProtectThreshold && desired.Selector == detail.Selector {
+		return nil
+	}
+
+	metadata, err := json.Marshal(desired.Metadata)
+	if err != nil {
+		return err
+	}
+	selector, err := json.Marshal(desired.Selector)
+	if err != nil {
+		return err
+	}
+
+	params := serviceParams(key, namespaceID)
+	params.Set("protectThreshold", strconv.FormatFloat(desired.ProtectThreshold, 'f', -1, 64))
+	params.Set("metadata", string(metadata))
+	params.Set("selector", string(selector))
+
+	method := http.MethodPut
+	if !exist {
+		method = http.MethodPost
+	}
+	code, data, err := a.request(method, nacosServicePath, params)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("apply service settings fail, code %d, body %s", code, data)
+	}
+	return nil
+}
+
+// metadataEqual returns whether the metadata are equal, and nil equals to empty.
+func metadataEqual(a, b map[string]string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
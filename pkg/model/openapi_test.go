@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != nacosCatalogPath || r.URL.Query().Get("namespaceId") != "dev" {
+			http.NotFound(w, r)
+			return
+		}
+
+		// The second page is the last one with a single service.
+		var services []string
+		count := 1
+		if r.URL.Query().Get("pageNo") == "1" {
+			count = catalogPageSize
+		}
+		for i := 0; i < count; i++ {
+			services = append(services, fmt.Sprintf(`{"name":"%s-%d","groupName":"g"}`, r.URL.Query().Get("pageNo"), i))
+		}
+		fmt.Fprintf(w, `{"count":%d,"serviceList":[%s]}`, catalogPageSize+1, strings.Join(services, ","))
+	}))
+	defer server.Close()
+
+	api := &nacosOpenAPI{servers: []string{strings.TrimPrefix(server.URL, "http://")}, client: server.Client()}
+	keys, err := api.listServices("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != catalogPageSize+1 {
+		t.Fatalf("got %d services, want %d", len(keys), catalogPageSize+1)
+	}
+	if last := keys[len(keys)-1]; last != (ServiceKey{ServiceName: "2-0", Group: "g"}) {
+		t.Errorf("got last service %+v", last)
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// GeneratePodServiceInfo generates the service info from the annotations of pod which is synced
+// without k8s service. Unlike service, the name annotation is required.
+func GeneratePodServiceInfo(pod *v1.Pod, options SyncOptions) (ServiceInfo, error) {
+	if annotationOf(pod.Annotations, annotationServiceName) == "" {
+		return ServiceInfo{}, fmt.Errorf("the service name annotation of pod (%s:%s) is required",
+			pod.Name, pod.Namespace)
+	}
+
+	return GenerateObjectInfo(pod, 0, options)
+}
+
+// PodServiceKeyOf returns the key of nacos service which the pod is synced to, in the same way as
+// generating its service info. It returns false if the pod should not be synced.
+func PodServiceKeyOf(pod *v1.Pod, options SyncOptions) (ServiceKey, bool) {
+	if !ShouldObjectSync(pod) {
+		return ServiceKey{}, false
+	}
+
+	serviceInfo, err := GeneratePodServiceInfo(pod, options)
+	if err != nil {
+		return ServiceKey{}, false
+	}
+	return serviceInfo.ServiceKey, true
+}
+
+// ConvertPodToAddress builds the address of pod according to its readiness and the not ready policy.
+// It returns false if the pod should not be registered.
+func ConvertPodToAddress(serviceInfo ServiceInfo, pod *v1.Pod) (Address, bool) {
+	if pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+		return Address{}, false
+	}
+
+	ready := isPodReady(pod)
+	if !ready && serviceInfo.NotReadyPolicy == NotReadyOmit {
+		return Address{}, false
+	}
+
+	return Address{
+		IP:          pod.Status.PodIP,
+		Port:        serviceInfo.Port,
+		Healthy:     ready || serviceInfo.NotReadyPolicy != NotReadyUnhealthy,
+		Enable:      ready || serviceInfo.NotReadyPolicy != NotReadyDisabled,
+		Weight:      serviceInfo.InstanceWeight(),
+		ClusterName: serviceInfo.ClusterName,
+		NodeName:    pod.Spec.NodeName,
+		PodName:     pod.Name,
+		Metadata:    serviceInfo.Metadata,
+	}, true
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
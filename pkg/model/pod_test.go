@@ -0,0 +1,90 @@
+package model
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodServiceKeyOf(t *testing.T) {
+	options := SyncOptions{GroupTemplate: "{{.Namespace}}-group"}
+	if err := options.Complete(); err != nil {
+		t.Fatal(err)
+	}
+
+	podOf := func(namespace string, annotations map[string]string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo-0",
+			Namespace:   namespace,
+			Annotations: map[string]string{"nacos.io/service-sync": "true", "nacos.io/service-port": "8080"},
+		}}
+		for key, value := range annotations {
+			pod.Annotations[key] = value
+		}
+		return pod
+	}
+
+	cases := []struct {
+		name   string
+		pod    *v1.Pod
+		want   ServiceKey
+		synced bool
+	}{
+		{
+			name:   "group from template",
+			pod:    podOf("a", map[string]string{"nacos.io/service-name": "foo"}),
+			want:   ServiceKey{ServiceName: "foo", Group: "a-group"},
+			synced: true,
+		},
+		{
+			name:   "same name in another namespace",
+			pod:    podOf("b", map[string]string{"nacos.io/service-name": "foo"}),
+			want:   ServiceKey{ServiceName: "foo", Group: "b-group"},
+			synced: true,
+		},
+		{
+			name: "group annotated",
+			pod: podOf("a", map[string]string{
+				"nacos.io/service-name":  "foo",
+				"nacos.io/service-group": "bar",
+			}),
+			want:   ServiceKey{ServiceName: "foo", Group: "bar"},
+			synced: true,
+		},
+		{
+			name: "nacos namespace",
+			pod: podOf("a", map[string]string{
+				"nacos.io/service-name":    "foo",
+				"nacos.io/nacos-namespace": "dev",
+			}),
+			want:   ServiceKey{Namespace: "dev", ServiceName: "foo", Group: "a-group"},
+			synced: true,
+		},
+		{
+			name: "name required",
+			pod:  podOf("a", nil),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key, synced := PodServiceKeyOf(c.pod, options)
+			if synced != c.synced || key != c.want {
+				t.Fatalf("got (%+v, %v), want (%+v, %v)", key, synced, c.want, c.synced)
+			}
+			if !synced {
+				return
+			}
+
+			// The pods are grouped by the key of the service info registered from them.
+			info, err := GeneratePodServiceInfo(c.pod, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ServiceKey != key {
+				t.Errorf("service key %+v, want %+v", info.ServiceKey, key)
+			}
+		})
+	}
+}
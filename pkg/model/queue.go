@@ -0,0 +1,77 @@
+package model
+
+import (
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+)
+
+// maxRetry is the times to retry a failed task, which can be changed at runtime.
+var maxRetry int32 = DefaultMaxRetry
+
+// SetMaxRetry changes the times to retry a failed task.
+func SetMaxRetry(retry int) {
+	atomic.StoreInt32(&maxRetry, int32(retry))
+}
+
+// RegisterHandlersForInformer puts the events of informer into queue as tasks handled by the handler.
+func RegisterHandlersForInformer(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface,
+	handler func(interface{}, interface{}, Event) error) {
+
+	informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				queue.Add(&Task{
+					Handler: func() error {
+						return handler(nil, obj, EventAdd)
+					},
+				})
+			},
+			UpdateFunc: func(old, cur interface{}) {
+				queue.Add(&Task{
+					Handler: func() error {
+						return handler(old, cur, EventUpdate)
+					},
+				})
+			},
+			DeleteFunc: func(obj interface{}) {
+				queue.Add(&Task{
+					Handler: func() error {
+						return handler(nil, obj, EventDelete)
+					},
+				})
+			},
+		})
+}
+
+// ProcessQueueTask handles a task from queue, and retries it with delay if it fails.
+func ProcessQueueTask(queue workqueue.RateLimitingInterface) {
+	obj, shutdown := queue.Get()
+	defer queue.Done(obj)
+
+	if shutdown {
+		return
+	}
+
+	task, ok := obj.(*Task)
+	if !ok {
+		logger.Warn("Convert to task fail.")
+		return
+	}
+
+	if err := task.Handler(); err != nil {
+		if queue.NumRequeues(obj) < int(atomic.LoadInt32(&maxRetry)) {
+			time.AfterFunc(DefaultTaskDelay, func() {
+				logger.Warnf("Task handle fail and put into queue again, err %v", err)
+				queue.AddRateLimited(obj)
+			})
+		} else {
+			logger.Warn("Task handle retry reach max times.")
+			queue.Forget(obj)
+		}
+	}
+}
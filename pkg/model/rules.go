@@ -0,0 +1,154 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/nacos-group/nacos-sdk-go/clients"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+)
+
+// SyncRules are the rules shared by all the services synced, which can be changed at runtime.
+type SyncRules struct {
+	// NamespaceMapping maps the k8s namespace to the nacos namespace.
+	NamespaceMapping map[string]string `json:"namespaceMapping,omitempty"`
+
+	// IncludeNamespaces are the k8s namespaces synced. Empty means all.
+	IncludeNamespaces []string `json:"includeNamespaces,omitempty"`
+
+	// ExcludeNamespaces are the k8s namespaces not synced.
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+
+	// LabelSelector selects the services synced by their labels.
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// ServiceNameTemplate and GroupTemplate are go templates to generate the name and group of
+	// nacos service from the object, such as {{.Namespace}}-{{.Name}} or {{.Labels.team}}.
+	ServiceNameTemplate string `json:"serviceNameTemplate,omitempty"`
+	GroupTemplate       string `json:"groupTemplate,omitempty"`
+
+	// DefaultMetadata is the metadata of all the services, which is overridden by the meta annotation.
+	DefaultMetadata map[string]string `json:"defaultMetadata,omitempty"`
+
+	// CELRules decide whether and how to sync the services, and the first one selecting the service
+	// takes effect.
+	CELRules []CELRule `json:"celRules,omitempty"`
+
+	selector    labels.Selector
+	keyTemplate *ServiceKeyTemplate
+	celPrograms []celProgram
+}
+
+// ParseSyncRules parses the rules formatted as yaml or json.
+func ParseSyncRules(content string) (*SyncRules, error) {
+	rules := &SyncRules{}
+	if err := yaml.Unmarshal([]byte(content), rules); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if rules.selector, err = labels.Parse(rules.LabelSelector); err != nil {
+		return nil, err
+	}
+	if rules.keyTemplate, err = ParseServiceKeyTemplate(rules.ServiceNameTemplate, rules.GroupTemplate); err != nil {
+		return nil, err
+	}
+	if rules.celPrograms, err = compileCELRules(rules.CELRules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Match returns whether the object is selected by the rules. Nil rules match everything.
+func (r *SyncRules) Match(obj metav1.Object) bool {
+	if r == nil {
+		return true
+	}
+
+	for _, namespace := range r.ExcludeNamespaces {
+		if obj.GetNamespace() == namespace {
+			return false
+		}
+	}
+
+	if len(r.IncludeNamespaces) > 0 {
+		included := false
+		for _, namespace := range r.IncludeNamespaces {
+			if obj.GetNamespace() == namespace {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	return r.selector == nil || r.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func (r *SyncRules) serviceKeyTemplate() *ServiceKeyTemplate {
+	if r == nil {
+		return nil
+	}
+
+	return r.keyTemplate
+}
+
+// apply fills the namespace and metadata of the service info generated from the object with the rules.
+// The metadata explicitly specified takes precedence.
+func (r *SyncRules) apply(obj metav1.Object, info *ServiceInfo) {
+	if r == nil {
+		return
+	}
+
+	if info.Namespace == "" {
+		info.Namespace = r.NamespaceMapping[obj.GetNamespace()]
+	}
+
+	if len(r.DefaultMetadata) > 0 {
+		info.Metadata = MergeMetadata(r.DefaultMetadata, info.Metadata)
+	}
+}
+
+// WatchSyncRules loads the sync rules from nacos config and listens to the changes of them. The
+// invalid rules are ignored with the previous ones kept.
+func WatchSyncRules(options NacosOptions, dataID, group string, onChange func(*SyncRules)) (*SyncRules, error) {
+	client, err := clients.NewConfigClient(ConvertToNacosClientParam(options))
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := client.GetConfig(vo.ConfigParam{DataId: dataID, Group: group})
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseSyncRules(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse sync rules (%s:%s) fail, err %v", dataID, group, err)
+	}
+
+	if err := client.ListenConfig(vo.ConfigParam{
+		DataId: dataID,
+		Group:  group,
+		OnChange: func(_, _, _, data string) {
+			rules, err := ParseSyncRules(data)
+			if err != nil {
+				logger.Errorf("Parse sync rules (%s:%s) fail, err %v.", dataID, group, err)
+				return
+			}
+			logger.Infof("Sync rules (%s:%s) changed.", dataID, group)
+			onChange(rules)
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Load sync rules (%s:%s).", dataID, group)
+	return rules, nil
+}
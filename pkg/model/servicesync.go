@@ -0,0 +1,223 @@
+package model
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// NacosServiceSyncIndex indexes the NacosServiceSyncs by the k8s service referenced.
+	NacosServiceSyncIndex = "nacosServiceRef"
+
+	ServiceSyncSynced ServiceSyncPhase = "Synced"
+	ServiceSyncFailed ServiceSyncPhase = "Failed"
+)
+
+var NacosServiceSyncResource = schema.GroupVersionResource{
+	Group:    "nacos.io",
+	Version:  "v1alpha1",
+	Resource: "nacosservicesyncs",
+}
+
+// ServiceSyncPhase is the outcome of syncing the service referenced by NacosServiceSync.
+type ServiceSyncPhase string
+
+// NacosServiceSync configures how to sync a k8s service to nacos, which takes precedence
+// over the annotations of the service.
+type NacosServiceSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NacosServiceSyncSpec   `json:"spec"`
+	Status NacosServiceSyncStatus `json:"status,omitempty"`
+}
+
+type NacosServiceSyncSpec struct {
+	// ServiceRef references the k8s service in the same namespace.
+	ServiceRef ServiceReference `json:"serviceRef"`
+
+	// ServiceName is the name of nacos service. Default is the name of k8s service.
+	ServiceName string `json:"serviceName,omitempty"`
+
+	Group string `json:"group,omitempty"`
+
+	// Namespace is the nacos namespace. Default is the one mapped by the sync rules or the one of syncer.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Port is the name or number of the service port, whose target port is registered.
+	// Default is the first service port.
+	Port *intstr.IntOrString `json:"port,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	Cluster string `json:"cluster,omitempty"`
+
+	// Weight is the default weight of instances, which can be overridden by the pods.
+	Weight *float64 `json:"weight,omitempty"`
+
+	Ephemeral *bool `json:"ephemeral,omitempty"`
+
+	NotReadyPolicy NotReadyPolicy `json:"notReadyPolicy,omitempty"`
+
+	AddressMode AddressMode `json:"addressMode,omitempty"`
+}
+
+type ServiceReference struct {
+	Name string `json:"name"`
+}
+
+type NacosServiceSyncStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	Phase ServiceSyncPhase `json:"phase,omitempty"`
+
+	// Message is the reason of failure.
+	Message string `json:"message,omitempty"`
+
+	// ServiceName and Group are the nacos service registered.
+	ServiceName string `json:"serviceName,omitempty"`
+	Group       string `json:"group,omitempty"`
+}
+
+// ParseNacosServiceSync converts the unstructured object into NacosServiceSync.
+func ParseNacosServiceSync(obj *unstructured.Unstructured) (*NacosServiceSync, error) {
+	serviceSync := &NacosServiceSync{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, serviceSync); err != nil {
+		return nil, err
+	}
+
+	return serviceSync, nil
+}
+
+// ToUnstructured converts the NacosServiceSync into unstructured object.
+func (s *NacosServiceSync) ToUnstructured() (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// NacosServiceSyncIndexFunc indexes the NacosServiceSync by namespace/name of the k8s service referenced.
+func NacosServiceSyncIndexFunc(obj interface{}) ([]string, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+
+	name, _, _ := unstructured.NestedString(u.Object, "spec", "serviceRef", "name")
+	if name == "" {
+		return nil, nil
+	}
+
+	return []string{u.GetNamespace() + "/" + name}, nil
+}
+
+// GenerateServiceSyncInfo generates the service info from the spec of NacosServiceSync, and the unspecified
+// fields fall back to the sync options.
+func GenerateServiceSyncInfo(svc *v1.Service, serviceSync *NacosServiceSync, options SyncOptions) (ServiceInfo, error) {
+	spec := serviceSync.Spec
+	port, err := serviceSyncPort(svc, spec.Port)
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+
+	serviceName := spec.ServiceName
+	if serviceName == "" {
+		serviceName = svc.Name
+	}
+
+	var weight float64
+	if spec.Weight != nil {
+		if *spec.Weight < 0 {
+			return ServiceInfo{}, fmt.Errorf("invalid instance weight %v", *spec.Weight)
+		}
+		weight = *spec.Weight
+	}
+
+	ephemeral := options.Ephemeral
+	if spec.Ephemeral != nil {
+		ephemeral = *spec.Ephemeral
+	}
+
+	notReadyPolicy := spec.NotReadyPolicy
+	if notReadyPolicy == "" {
+		notReadyPolicy = options.NotReadyPolicy
+	}
+	notReadyPolicy, err = resolveNotReadyPolicy(notReadyPolicy, ephemeral)
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+
+	addressMode := spec.AddressMode
+	switch addressMode {
+	case AddressModePod, AddressModeClusterIP, AddressModeNodePort, AddressModeLoadBalancer:
+	case "":
+		addressMode = AddressModePod
+	default:
+		return ServiceInfo{}, fmt.Errorf("not supported address mode %s", addressMode)
+	}
+
+	serviceInfo := ServiceInfo{
+		ServiceKey: ServiceKey{
+			ServiceName: serviceName,
+			Group:       spec.Group,
+			Namespace:   spec.Namespace,
+		},
+		Port:           port,
+		Metadata:       spec.Metadata,
+		Ephemeral:      ephemeral,
+		NotReadyPolicy: notReadyPolicy,
+		Weight:         weight,
+		ClusterName:    spec.Cluster,
+		AddressMode:    addressMode,
+	}
+	if err := applyServiceKeyTemplates(svc, &serviceInfo, spec.ServiceName != "", spec.Group != "", options); err != nil {
+		return ServiceInfo{}, err
+	}
+	options.Rules.apply(svc, &serviceInfo)
+
+	return serviceInfo, nil
+}
+
+// serviceSyncPort resolves the port registered, which is the target port of the service port.
+func serviceSyncPort(svc *v1.Service, port *intstr.IntOrString) (uint64, error) {
+	var servicePort *v1.ServicePort
+	for i := range svc.Spec.Ports {
+		p := &svc.Spec.Ports[i]
+		if port == nil ||
+			(port.Type == intstr.Int && p.Port == port.IntVal) ||
+			(port.Type == intstr.String && p.Name == port.StrVal) {
+			servicePort = p
+			break
+		}
+	}
+
+	if servicePort == nil {
+		if port != nil && port.Type == intstr.Int && port.IntVal > 0 {
+			// The number is not a service port, so it is used as the target port directly.
+			return uint64(port.IntVal), nil
+		}
+		return 0, fmt.Errorf("port %v is not found in service (%s:%s)", port, svc.Name, svc.Namespace)
+	}
+
+	if servicePort.TargetPort.Type == intstr.String {
+		if servicePort.TargetPort.StrVal != "" {
+			return 0, fmt.Errorf("named target port %s of service (%s:%s) is not supported",
+				servicePort.TargetPort.StrVal, svc.Name, svc.Namespace)
+		}
+		return uint64(servicePort.Port), nil
+	}
+	if servicePort.TargetPort.IntVal == 0 {
+		return uint64(servicePort.Port), nil
+	}
+
+	return uint64(servicePort.TargetPort.IntVal), nil
+}
@@ -0,0 +1,103 @@
+package model
+
+import "time"
+
+type SyncOptions struct {
+	// Ephemeral determines whether the instances are registered as ephemeral instances
+	// by default, which can be overridden by the annotation of service.
+	// Persistent instances do not depend on the heartbeat of syncer, and their lifecycle
+	// is completely owned by syncer.
+	Ephemeral bool
+
+	// NotReadyPolicy determines how to register the not ready addresses by default.
+	// If it is empty, the not ready addresses of persistent instances are registered
+	// as unhealthy, and the ones of ephemeral instances are omitted.
+	NotReadyPolicy NotReadyPolicy
+
+	// MetadataPolicy determines how the metadata of instances are merged with the ones set
+	// outside syncer by default.
+	MetadataPolicy MetadataPolicy
+
+	// Guard protects the instances from being deregistered massively.
+	Guard GuardOptions
+
+	// DrainGracePeriod is how long the instances of terminating pods are kept in nacos
+	// as drained instances before being unregistered. Zero means that the instances are
+	// unregistered immediately.
+	DrainGracePeriod time.Duration
+
+	// DrainMode determines how to drain the instances of terminating pods.
+	DrainMode DrainMode
+
+	// ClusterFromTopology determines whether the nacos cluster of instances is derived from
+	// the topology zone of the nodes where the pods are running, if the cluster of service
+	// is not specified.
+	ClusterFromTopology bool
+
+	// PodLabelsAsMetadata are the keys of pod labels which are copied into the metadata
+	// of instances.
+	PodLabelsAsMetadata []string
+
+	// ClusterID identifies the k8s cluster which the instances belong to.
+	ClusterID string
+
+	// IdentityMetadata determines whether the k8s identity of instances, such as namespace,
+	// service, pod, node and cluster, is added into the metadata of instances.
+	IdentityMetadata bool
+
+	// SyncIngress determines whether to sync the annotated ingresses of networking.k8s.io/v1.
+	SyncIngress bool
+
+	// SyncHTTPRoute determines whether to sync the annotated http routes of gateway api.
+	SyncHTTPRoute bool
+
+	// SyncPod determines whether to sync the annotated pods without k8s service.
+	SyncPod bool
+
+	// IstioServices are the nacos services which are converted into istio service entries and
+	// workload entries. The format is name or name@@group.
+	IstioServices []string
+
+	// IstioNamespace is the namespace where the istio resources are created.
+	IstioNamespace string
+
+	// IstioHostSuffix is the suffix of the host of service entries, which are named as
+	// name.group.suffix.
+	IstioHostSuffix string
+
+	// MCS determines whether to act as the registry of multi-cluster services api, which exports
+	// the services with ServiceExport to nacos and imports the ones of other clusters as ServiceImport.
+	MCS bool
+
+	// MCSGroup is the nacos group of the services exported by multi-cluster services api.
+	MCSGroup string
+
+	// ServiceSyncCRD determines whether to sync the services referenced by NacosServiceSync, whose
+	// spec takes precedence over the annotations.
+	ServiceSyncCRD bool
+
+	// RulesDataID and RulesGroup locate the nacos config of sync rules, which are watched if specified.
+	RulesDataID string
+	RulesGroup  string
+
+	// Rules are the sync rules loaded from nacos config, which are replaced when the config changes.
+	Rules *SyncRules
+
+	// ServiceNameTemplate and GroupTemplate are the global go templates to generate the name and group
+	// of nacos service, which are overridden by the annotations.
+	ServiceNameTemplate string
+	GroupTemplate       string
+
+	keyTemplate *ServiceKeyTemplate
+}
+
+// Complete parses the options which must be validated before use.
+func (o *SyncOptions) Complete() error {
+	keyTemplate, err := ParseServiceKeyTemplate(o.ServiceNameTemplate, o.GroupTemplate)
+	if err != nil {
+		return err
+	}
+
+	o.keyTemplate = keyTemplate
+	return nil
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceKeyTemplate generates the name and group of nacos service from the object with go templates,
+// such as {{.Namespace}}-{{.Name}} or {{.Labels.team}}.
+type ServiceKeyTemplate struct {
+	name  *template.Template
+	group *template.Template
+}
+
+// templateData is the data which the templates of service name and group are executed with.
+type templateData struct {
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// ParseServiceKeyTemplate parses the templates of service name and group, and the empty one is ignored.
+func ParseServiceKeyTemplate(name, group string) (*ServiceKeyTemplate, error) {
+	var t ServiceKeyTemplate
+	var err error
+	if t.name, err = parseTemplate("serviceName", name); err != nil {
+		return nil, err
+	}
+	if t.group, err = parseTemplate("group", group); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// applyServiceKeyTemplates generates the name and group which are not specified by the object itself.
+// The templates of sync rules take precedence over the global ones.
+func applyServiceKeyTemplates(obj metav1.Object, info *ServiceInfo, nameSpecified, groupSpecified bool,
+	options SyncOptions) error {
+	var nameTemplate, groupTemplate *template.Template
+	for _, t := range []*ServiceKeyTemplate{options.keyTemplate, options.Rules.serviceKeyTemplate()} {
+		if t == nil {
+			continue
+		}
+		if t.name != nil {
+			nameTemplate = t.name
+		}
+		if t.group != nil {
+			groupTemplate = t.group
+		}
+	}
+
+	if !nameSpecified && nameTemplate != nil {
+		name, err := executeTemplate(nameTemplate, obj)
+		if err != nil {
+			return err
+		}
+		info.ServiceName = name
+	}
+
+	if !groupSpecified && groupTemplate != nil {
+		group, err := executeTemplate(groupTemplate, obj)
+		if err != nil {
+			return err
+		}
+		info.Group = group
+	}
+
+	return nil
+}
+
+func executeTemplate(t *template.Template, obj metav1.Object) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, templateData{
+		Name:        obj.GetName(),
+		Namespace:   obj.GetNamespace(),
+		Labels:      obj.GetLabels(),
+		Annotations: obj.GetAnnotations(),
+	}); err != nil {
+		return "", err
+	}
+
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("template %s of (%s:%s) is executed as empty", t.Name(), obj.GetName(), obj.GetNamespace())
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,353 @@
+package tok8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	lister "k8s.io/client-go/listers/core/v1"
+	discoverylister "k8s.io/client-go/listers/discovery/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+type Controller struct {
+	nacosClient model.NacosClient
+
+	kubeClient kubernetes.Interface
+
+	dynamicClient dynamic.Interface
+
+	syncOptions model.SyncOptions
+
+	serviceInformer cache.SharedIndexInformer
+	serviceLister   lister.ServiceLister
+
+	endpointSliceInformer cache.SharedIndexInformer
+	endpointSliceLister   discoverylister.EndpointSliceLister
+
+	// importers are the k8s services which import the nacos service, keyed by namespace/name.
+	importers map[model.ServiceKey]map[string]struct{}
+
+	// imported is the nacos service imported by each k8s service.
+	imported map[string]model.ServiceKey
+
+	// istioServices are the nacos services converted into istio resources.
+	istioServices map[model.ServiceKey]struct{}
+
+	// mcsServices are the nacos services exported by multi-cluster services api.
+	mcsServices map[model.ServiceKey]struct{}
+
+	// subscribed are the nacos services which have been subscribed.
+	subscribed map[model.ServiceKey]struct{}
+
+	queue workqueue.RateLimitingInterface
+
+	once sync.Once
+}
+
+func NewController(options model.NacosOptions, syncOptions model.SyncOptions,
+	kubeClient model.KubeClient) (model.Controller, error) {
+	if len(syncOptions.IstioServices) > 0 && syncOptions.IstioNamespace == "" {
+		return nil, fmt.Errorf("the namespace of istio resources is required")
+	}
+
+	// The local instances are recognized by the cluster id, which must not be imported.
+	if syncOptions.MCS && syncOptions.ClusterID == "" {
+		return nil, fmt.Errorf("multi-cluster services require the cluster id")
+	}
+
+	nacosClient, err := model.NewNacosClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Controller{
+		nacosClient:   nacosClient,
+		kubeClient:    kubeClient.Kubernetes(),
+		dynamicClient: kubeClient.Dynamic(),
+		syncOptions:   syncOptions,
+		importers:     make(map[model.ServiceKey]map[string]struct{}),
+		imported:      make(map[string]model.ServiceKey),
+		istioServices: make(map[model.ServiceKey]struct{}),
+		mcsServices:   make(map[model.ServiceKey]struct{}),
+		subscribed:    make(map[model.ServiceKey]struct{}),
+	}
+	for _, raw := range syncOptions.IstioServices {
+		c.istioServices[model.ParseServiceKey(raw)] = struct{}{}
+	}
+
+	c.queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	// list and watch service
+	c.serviceInformer = kubeClient.InformerFactory().Core().V1().Services().Informer()
+	c.serviceLister = kubeClient.InformerFactory().Core().V1().Services().Lister()
+	model.RegisterHandlersForInformer(c.serviceInformer, c.queue, c.onServiceEvent)
+	// list endpoint slices
+	c.endpointSliceInformer = kubeClient.InformerFactory().Discovery().V1beta1().EndpointSlices().Informer()
+	c.endpointSliceLister = kubeClient.InformerFactory().Discovery().V1beta1().EndpointSlices().Lister()
+
+	return c, nil
+}
+
+func (c *Controller) onServiceEvent(_, curr interface{}, event model.Event) error {
+	service, ok := curr.(*v1.Service)
+	if !ok {
+		return nil
+	}
+
+	key := service.Namespace + "/" + service.Name
+	serviceKey, importing := model.ImportServiceKey(service)
+	if event == model.EventDelete {
+		// The endpoint slices are deleted by garbage collector with the service.
+		c.stopImport(key)
+		return nil
+	}
+
+	if !importing {
+		if _, exist := c.imported[key]; exist {
+			c.stopImport(key)
+			return c.applyEndpointSlices(service.Namespace, serviceSliceLabels(service.Name), nil)
+		}
+		return nil
+	}
+
+	// The instances registered from the service itself are told apart by the identity metadata,
+	// otherwise its own pods are imported back into it.
+	if !c.syncOptions.IdentityMetadata {
+		logger.Errorf("Import nacos service (%s@@%s) into service (%s:%s) fail, identity metadata is required.",
+			serviceKey.ServiceName, serviceKey.Group, service.Name, service.Namespace)
+		return nil
+	}
+
+	if old, exist := c.imported[key]; exist && old != serviceKey {
+		c.stopImport(key)
+	}
+	if err := c.startImport(key, serviceKey); err != nil {
+		logger.Errorf("Import nacos service (%s@@%s) into service (%s:%s) fail, err %v.",
+			serviceKey.ServiceName, serviceKey.Group, service.Name, service.Namespace, err)
+		return err
+	}
+
+	return c.syncService(service)
+}
+
+// subscribe watches the nacos service once, and syncs all the k8s resources converted from it
+// when it changes.
+func (c *Controller) subscribe(serviceKey model.ServiceKey) error {
+	if _, exist := c.subscribed[serviceKey]; exist {
+		return nil
+	}
+
+	if err := c.nacosClient.Subscribe(serviceKey, func() {
+		c.queue.Add(&model.Task{
+			Handler: func() error {
+				return c.onNacosServiceChanged(serviceKey)
+			},
+		})
+	}); err != nil {
+		return err
+	}
+
+	c.subscribed[serviceKey] = struct{}{}
+	return nil
+}
+
+// unsubscribe stops watching the nacos service if nothing is converted from it.
+func (c *Controller) unsubscribe(serviceKey model.ServiceKey) {
+	if _, exist := c.istioServices[serviceKey]; exist || len(c.importers[serviceKey]) > 0 {
+		return
+	}
+	if _, exist := c.mcsServices[serviceKey]; exist {
+		return
+	}
+
+	delete(c.subscribed, serviceKey)
+	c.nacosClient.Unsubscribe(serviceKey)
+}
+
+func (c *Controller) startImport(key string, serviceKey model.ServiceKey) error {
+	if err := c.subscribe(serviceKey); err != nil {
+		return err
+	}
+
+	if c.importers[serviceKey] == nil {
+		c.importers[serviceKey] = make(map[string]struct{})
+	}
+	c.importers[serviceKey][key] = struct{}{}
+	c.imported[key] = serviceKey
+	return nil
+}
+
+func (c *Controller) stopImport(key string) {
+	serviceKey, exist := c.imported[key]
+	if !exist {
+		return
+	}
+
+	delete(c.imported, key)
+	delete(c.importers[serviceKey], key)
+	if len(c.importers[serviceKey]) == 0 {
+		delete(c.importers, serviceKey)
+	}
+	c.unsubscribe(serviceKey)
+}
+
+func (c *Controller) onNacosServiceChanged(serviceKey model.ServiceKey) error {
+	var errs *multierror.Error
+	if _, exist := c.istioServices[serviceKey]; exist {
+		errs = multierror.Append(errs, c.syncIstioService(serviceKey))
+	}
+
+	if _, exist := c.mcsServices[serviceKey]; exist {
+		errs = multierror.Append(errs, c.syncMCSService(serviceKey))
+	}
+
+	for key := range c.importers[serviceKey] {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			continue
+		}
+
+		service, err := c.serviceLister.Services(namespace).Get(name)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				errs = multierror.Append(errs, err)
+			}
+			continue
+		}
+		errs = multierror.Append(errs, c.syncService(service))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// syncService maintains the endpoint slices of k8s service with the instances of nacos service.
+func (c *Controller) syncService(service *v1.Service) error {
+	serviceKey, importing := model.ImportServiceKey(service)
+	if !importing {
+		return nil
+	}
+
+	addresses, err := c.nacosClient.SelectAllInstances(serviceKey)
+	if err != nil {
+		return err
+	}
+
+	// The instances registered from the service itself must not be imported again.
+	var imported []model.Address
+	for _, address := range addresses {
+		if !model.IsOwnInstance(address, service.Namespace, service.Name, c.syncOptions.ClusterID) {
+			imported = append(imported, address)
+		}
+	}
+
+	logger.Infof("Import %d instances of nacos service (%s@@%s) into service (%s:%s).",
+		len(imported), serviceKey.ServiceName, serviceKey.Group, service.Name, service.Namespace)
+	return c.applyEndpointSlices(service.Namespace, serviceSliceLabels(service.Name),
+		model.ConvertToEndpointSlices(service, imported))
+}
+
+// serviceSliceLabels selects the endpoint slices of k8s service maintained by syncer.
+func serviceSliceLabels(name string) labels.Set {
+	return labels.Set{
+		discoveryv1beta1.LabelServiceName: name,
+		discoveryv1beta1.LabelManagedBy:   model.EndpointSliceManagedBy,
+	}
+}
+
+// applyEndpointSlices creates or updates the desired endpoint slices, and deletes the stale ones
+// selected by the labels.
+func (c *Controller) applyEndpointSlices(namespace string, selector labels.Set,
+	desired []*discoveryv1beta1.EndpointSlice) error {
+	existing, err := c.endpointSliceLister.EndpointSlices(namespace).List(labels.SelectorFromSet(selector))
+	if err != nil {
+		return err
+	}
+
+	existingMap := make(map[string]*discoveryv1beta1.EndpointSlice, len(existing))
+	for _, slice := range existing {
+		existingMap[slice.Name] = slice
+	}
+
+	var errs *multierror.Error
+	client := c.kubeClient.DiscoveryV1beta1().EndpointSlices(namespace)
+	for _, slice := range desired {
+		old, exist := existingMap[slice.Name]
+		delete(existingMap, slice.Name)
+		if !exist {
+			_, err = client.Create(context.TODO(), slice, metav1.CreateOptions{})
+		} else if !apiequality.Semantic.DeepEqual(old.Endpoints, slice.Endpoints) ||
+			!apiequality.Semantic.DeepEqual(old.Ports, slice.Ports) {
+			updated := old.DeepCopy()
+			updated.Endpoints = slice.Endpoints
+			updated.Ports = slice.Ports
+			_, err = client.Update(context.TODO(), updated, metav1.UpdateOptions{})
+		}
+		errs = multierror.Append(errs, err)
+	}
+
+	for name := range existingMap {
+		err = client.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func (c *Controller) syncAllServiceToK8s() error {
+	var err *multierror.Error
+
+	services := c.serviceInformer.GetStore().List()
+	for _, service := range services {
+		err = multierror.Append(err, c.onServiceEvent(nil, service, model.EventAdd))
+	}
+
+	err = multierror.Append(err, c.syncAllIstioServices())
+
+	if c.syncOptions.MCS {
+		err = multierror.Append(err, c.syncAllMCSServices())
+	}
+
+	return multierror.Flatten(err.ErrorOrNil())
+}
+
+func (c *Controller) HasSynced() bool {
+	if !c.serviceInformer.HasSynced() || !c.endpointSliceInformer.HasSynced() {
+		return false
+	}
+
+	t0 := time.Now()
+	c.once.Do(func() {
+		if err := c.syncAllServiceToK8s(); err != nil {
+			return
+		}
+	})
+
+	logger.Infof("Have Synced all services to k8s, cost %s.", time.Since(t0))
+	return true
+}
+
+func (c *Controller) Run(stop <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	cache.WaitForCacheSync(stop, c.HasSynced)
+
+	wait.Until(func() { model.ProcessQueueTask(c.queue) }, 0, stop)
+}
@@ -0,0 +1,125 @@
+package tok8s
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// syncAllIstioServices subscribes the nacos services which are converted into istio resources.
+func (c *Controller) syncAllIstioServices() error {
+	var errs *multierror.Error
+	for serviceKey := range c.istioServices {
+		if err := c.subscribe(serviceKey); err != nil {
+			logger.Errorf("Subscribe nacos service (%s@@%s) for istio fail, err %v.",
+				serviceKey.ServiceName, serviceKey.Group, err)
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		errs = multierror.Append(errs, c.syncIstioService(serviceKey))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// syncIstioService maintains the service entry and workload entries of the nacos service.
+func (c *Controller) syncIstioService(serviceKey model.ServiceKey) error {
+	addresses, err := c.nacosClient.SelectAllInstances(serviceKey)
+	if err != nil {
+		return err
+	}
+
+	namespace := c.syncOptions.IstioNamespace
+	if len(addresses) == 0 {
+		// The service entry without ports is invalid, and the workload entries are deleted by
+		// garbage collector with it.
+		err = c.dynamicClient.Resource(model.ServiceEntryResource).Namespace(namespace).
+			Delete(context.TODO(), model.IstioResourceName(serviceKey), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	serviceEntry, err := c.applyUnstructured(model.ServiceEntryResource,
+		model.ConvertToServiceEntry(serviceKey, namespace, c.syncOptions.IstioHostSuffix, addresses))
+	if err != nil {
+		return err
+	}
+
+	desired := model.ConvertToWorkloadEntries(serviceKey, namespace, addresses)
+	logger.Infof("Convert %d instances of nacos service (%s@@%s) into workload entries.",
+		len(desired), serviceKey.ServiceName, serviceKey.Group)
+
+	existing, err := c.dynamicClient.Resource(model.WorkloadEntryResource).Namespace(namespace).List(context.TODO(),
+		metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set{
+			model.LabelIstioService: serviceEntry.GetName(),
+		}).String()})
+	if err != nil {
+		return err
+	}
+
+	stale := make(map[string]struct{}, len(existing.Items))
+	for _, workloadEntry := range existing.Items {
+		stale[workloadEntry.GetName()] = struct{}{}
+	}
+
+	var errs *multierror.Error
+	for _, workloadEntry := range desired {
+		delete(stale, workloadEntry.GetName())
+		// The workload entries are deleted by garbage collector with the service entry.
+		workloadEntry.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: serviceEntry.GetAPIVersion(),
+			Kind:       serviceEntry.GetKind(),
+			Name:       serviceEntry.GetName(),
+			UID:        serviceEntry.GetUID(),
+		}})
+		_, err = c.applyUnstructured(model.WorkloadEntryResource, workloadEntry)
+		errs = multierror.Append(errs, err)
+	}
+
+	for name := range stale {
+		err = c.dynamicClient.Resource(model.WorkloadEntryResource).Namespace(namespace).
+			Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// applyUnstructured creates the object or updates it if its labels, owners or spec changed,
+// and returns the object in k8s.
+func (c *Controller) applyUnstructured(resource schema.GroupVersionResource,
+	obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	client := c.dynamicClient.Resource(resource).Namespace(obj.GetNamespace())
+	old, err := client.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return client.Create(context.TODO(), obj, metav1.CreateOptions{})
+		}
+		return nil, err
+	}
+
+	if apiequality.Semantic.DeepEqual(old.Object["spec"], obj.Object["spec"]) &&
+		apiequality.Semantic.DeepEqual(old.GetLabels(), obj.GetLabels()) &&
+		apiequality.Semantic.DeepEqual(old.GetOwnerReferences(), obj.GetOwnerReferences()) {
+		return old, nil
+	}
+
+	updated := old.DeepCopy()
+	updated.Object["spec"] = obj.Object["spec"]
+	updated.SetLabels(obj.GetLabels())
+	updated.SetOwnerReferences(obj.GetOwnerReferences())
+	return client.Update(context.TODO(), updated, metav1.UpdateOptions{})
+}
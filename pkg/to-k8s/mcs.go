@@ -0,0 +1,130 @@
+package tok8s
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// syncAllMCSServices discovers the nacos services exported by other clusters, imports the new ones
+// and removes the ServiceImports of the vanished ones. It runs periodically because nacos can not
+// notify the change of service list.
+func (c *Controller) syncAllMCSServices() error {
+	defer c.queue.AddAfter(&model.Task{Handler: c.syncAllMCSServices}, model.DefaultMCSResyncInterval)
+
+	serviceNames, err := c.nacosClient.ListServices(c.syncOptions.MCSGroup)
+	if err != nil {
+		logger.Errorf("List nacos services of group %s fail, err %v.", c.syncOptions.MCSGroup, err)
+		return nil
+	}
+
+	current := make(map[model.ServiceKey]struct{}, len(serviceNames))
+	var errs *multierror.Error
+	for _, serviceName := range serviceNames {
+		if _, _, ok := model.ParseMCSServiceName(serviceName); !ok {
+			continue
+		}
+
+		serviceKey := model.ServiceKey{ServiceName: serviceName, Group: c.syncOptions.MCSGroup}
+		current[serviceKey] = struct{}{}
+		if _, exist := c.mcsServices[serviceKey]; exist {
+			continue
+		}
+
+		if err := c.subscribe(serviceKey); err != nil {
+			logger.Errorf("Subscribe nacos service (%s@@%s) for multi-cluster services fail, err %v.",
+				serviceKey.ServiceName, serviceKey.Group, err)
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		c.mcsServices[serviceKey] = struct{}{}
+		errs = multierror.Append(errs, c.syncMCSService(serviceKey))
+	}
+
+	for serviceKey := range c.mcsServices {
+		if _, exist := current[serviceKey]; exist {
+			continue
+		}
+
+		delete(c.mcsServices, serviceKey)
+		c.unsubscribe(serviceKey)
+		errs = multierror.Append(errs, c.deleteServiceImport(serviceKey))
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		logger.Errorf("Sync multi-cluster services fail, err %v.", err)
+	}
+	// The failed services are retried in the next round.
+	return nil
+}
+
+// syncMCSService maintains the ServiceImport and its endpoint slices with the instances of nacos service
+// registered by other clusters.
+func (c *Controller) syncMCSService(serviceKey model.ServiceKey) error {
+	namespace, name, ok := model.ParseMCSServiceName(serviceKey.ServiceName)
+	if !ok {
+		return nil
+	}
+
+	addresses, err := c.nacosClient.SelectAllInstances(serviceKey)
+	if err != nil {
+		return err
+	}
+
+	var remote []model.Address
+	for _, address := range addresses {
+		clusterID := address.Metadata[model.MetadataKubeCluster]
+		if clusterID != "" && clusterID != c.syncOptions.ClusterID {
+			remote = append(remote, address)
+		}
+	}
+	if len(remote) == 0 {
+		return c.deleteServiceImport(serviceKey)
+	}
+
+	serviceImport, err := c.applyUnstructured(model.ServiceImportResource,
+		model.ConvertToServiceImport(namespace, name, remote))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The namespace does not exist in this cluster.
+			logger.Warnf("Import multi-cluster service (%s:%s) fail, err %v.", name, namespace, err)
+			return nil
+		}
+		return err
+	}
+
+	logger.Infof("Import %d instances of multi-cluster service (%s:%s).", len(remote), name, namespace)
+	// The endpoint slices are deleted by garbage collector with the ServiceImport.
+	owner := metav1.OwnerReference{
+		APIVersion: serviceImport.GetAPIVersion(),
+		Kind:       serviceImport.GetKind(),
+		Name:       serviceImport.GetName(),
+		UID:        serviceImport.GetUID(),
+	}
+	return c.applyEndpointSlices(namespace, labels.Set{
+		model.LabelMCSServiceName:       name,
+		discoveryv1beta1.LabelManagedBy: model.EndpointSliceManagedBy,
+	}, model.ConvertToMCSEndpointSlices(namespace, name, c.syncOptions.ClusterID, owner, remote))
+}
+
+func (c *Controller) deleteServiceImport(serviceKey model.ServiceKey) error {
+	namespace, name, ok := model.ParseMCSServiceName(serviceKey.ServiceName)
+	if !ok {
+		return nil
+	}
+
+	err := c.dynamicClient.Resource(model.ServiceImportResource).Namespace(namespace).
+		Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package tonacos
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// buildServiceAddresses builds the addresses from the cluster ip, node ports or load balancer
+// of service according to the address mode.
+func (c *Controller) buildServiceAddresses(service *v1.Service, serviceInfo model.ServiceInfo) ([]model.Address, error) {
+	var nodes []*v1.Node
+	if serviceInfo.AddressMode == model.AddressModeNodePort {
+		if err := c.waitNodes(); err != nil {
+			return nil, err
+		}
+		var err error
+		if nodes, err = c.nodeLister.List(labels.Everything()); err != nil {
+			return nil, err
+		}
+	}
+
+	return model.ConvertServiceToAddresses(serviceInfo, service, nodes)
+}
+
+// watchNodes registers the node informer, which is started with the other informers if the kube
+// client is not running yet.
+func (c *Controller) watchNodes() {
+	if c.nodeInformer != nil {
+		return
+	}
+
+	c.nodeInformer = c.kubeClient.InformerFactory().Core().V1().Nodes().Informer()
+	c.nodeLister = c.kubeClient.InformerFactory().Core().V1().Nodes().Lister()
+	model.RegisterHandlersForInformer(c.nodeInformer, c.queue, c.onNodeEvent)
+}
+
+// waitNodes starts the node informer on demand and waits until it is synced.
+func (c *Controller) waitNodes() error {
+	if c.nodeInformer != nil && c.nodeInformer.HasSynced() {
+		return nil
+	}
+
+	logger.Info("Service registers node port addresses, start watching nodes.")
+	c.watchNodes()
+	c.kubeClient.InformerFactory().Start(c.stop)
+	if !cache.WaitForCacheSync(c.stop, c.nodeInformer.HasSynced) {
+		return fmt.Errorf("wait for nodes synced fail")
+	}
+	return nil
+}
+
+func (c *Controller) onNodeEvent(old, curr interface{}, event model.Event) error {
+	node, ok := curr.(*v1.Node)
+	if !ok {
+		return nil
+	}
+
+	// Only the changes of addresses and readiness of nodes affect the node port addresses, and the
+	// changes of zone affect the clusters derived from topology.
+	zoneChanged := false
+	if event == model.EventUpdate {
+		oldNode, ok := old.(*v1.Node)
+		if !ok {
+			return nil
+		}
+		zoneChanged = c.syncOptions.ClusterFromTopology && zoneOf(oldNode) != zoneOf(node)
+		if !zoneChanged && reflect.DeepEqual(oldNode.Status.Addresses, node.Status.Addresses) &&
+			model.IsNodeReady(oldNode) == model.IsNodeReady(node) {
+			return nil
+		}
+	}
+
+	services, err := c.serviceLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, service := range services {
+		shouldSync, serviceInfo, err := c.serviceInfoOf(service)
+		if !shouldSync || err != nil {
+			continue
+		}
+		if serviceInfo.AddressMode != model.AddressModeNodePort && !(zoneChanged &&
+			serviceInfo.AddressMode == model.AddressModePod && serviceInfo.ClusterName == "") {
+			continue
+		}
+
+		logger.Infof("Node (%s) changed, resync service (%s:%s).", node.Name, service.Name, service.Namespace)
+		errs = multierror.Append(errs, c.onServiceEvent(nil, service, model.EventAdd))
+	}
+
+	if zoneChanged && c.syncOptions.SyncPod {
+		errs = multierror.Append(errs, c.syncAllPodServices())
+	}
+
+	return errs.ErrorOrNil()
+}
@@ -1,15 +1,19 @@
 package tonacos
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	lister "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
@@ -19,7 +23,19 @@ import (
 type Controller struct {
 	nacosClient model.NacosClient
 
-	watchedNamespace string
+	// nacosNamespace is the nacos namespace which the services are registered into.
+	nacosNamespace string
+
+	dynamicClient dynamic.Interface
+
+	// recorder records the errors of syncing services as events.
+	recorder record.EventRecorder
+
+	syncOptions model.SyncOptions
+
+	// kubeOptions decides the namespaces and labels of the services synced, whose filters can be
+	// reloaded at runtime.
+	kubeOptions model.KubeOptions
 
 	serviceInformer cache.SharedIndexInformer
 	serviceLister   lister.ServiceLister
@@ -27,61 +43,395 @@ type Controller struct {
 	endpointsInformer cache.SharedIndexInformer
 	endpointsLister   lister.EndpointsLister
 
+	podInformer cache.SharedIndexInformer
+	podLister   lister.PodLister
+
+	// scope are the informers above restricted by the filters of services, which are replaced
+	// when the filters change.
+	scope *informerScope
+
+	// nodeInformer is only started when the clusters come from topology or a service registers the
+	// node port addresses, because nodes are watched cluster wide.
+	nodeInformer cache.SharedIndexInformer
+	nodeLister   lister.NodeLister
+
+	namespaceInformer cache.SharedIndexInformer
+	namespaceLister   lister.NamespaceLister
+
+	// namespaces are the namespaces observed last, whose annotations are inherited by services and
+	// labels are evaluated by CEL rules. They are recorded to find the services affected by the changes.
+	namespaces map[string]*v1.Namespace
+
+	// pendingResyncs are the deadlines of the delayed resyncs of services keyed by namespace/name,
+	// so that the delayed resyncs of a service are not multiplied by its events.
+	pendingResyncs map[string]time.Time
+
+	// refusedUnregistrations are the unregistrations refused by guard, which are retried until they
+	// are allowed or the services are registered from the same sources again.
+	refusedUnregistrations map[unregistrationKey]refusedUnregistration
+
+	// ruleErrors are the errors of evaluating CEL rules reported last keyed by namespace/name of
+	// services, so that the same error is reported once until the rules or the services change.
+	ruleErrors map[string]string
+
+	// podServiceKeys are the keys of nacos services which the synced pods belong to keyed by
+	// namespace/name, and podServices are the synced pods of each nacos service. They are generated
+	// with the current templates and rules, so they are maintained by syncer instead of an index.
+	podServiceKeys map[string]model.ServiceKey
+	podServices    map[model.ServiceKey]map[string]struct{}
+
+	// registeredPodServices are the nacos services registered from pods.
+	registeredPodServices map[model.ServiceKey]registeredPodService
+
+	// ingressInformer is only used when the ingresses should be synced.
+	ingressInformer cache.SharedIndexInformer
+
+	// httpRouteInformer and gatewayInformer are only used when the http routes should be synced.
+	httpRouteInformer cache.SharedIndexInformer
+	httpRouteLister   cache.GenericLister
+	gatewayInformer   cache.SharedIndexInformer
+	gatewayLister     cache.GenericLister
+
+	// serviceExportInformer is only used when acting as the registry of multi-cluster services api.
+	serviceExportInformer cache.SharedIndexInformer
+	serviceExportLister   cache.GenericLister
+
+	// serviceSyncInformer is only used when the NacosServiceSyncs are watched.
+	serviceSyncInformer cache.SharedIndexInformer
+
 	queue workqueue.RateLimitingInterface
 
+	kubeClient model.KubeClient
+
+	// stop is the channel which stops the controller, and the informers started on demand.
+	stop <-chan struct{}
+
 	once sync.Once
 }
 
-func NewController(options model.NacosOptions, watchedNamespace string, kubeClient model.KubeClient) (model.Controller, error) {
+func NewController(options model.NacosOptions, syncOptions model.SyncOptions, kubeOptions model.KubeOptions,
+	kubeClient model.KubeClient) (model.Controller, error) {
+	if syncOptions.DrainMode != model.DrainDisable && syncOptions.DrainMode != model.DrainWeight {
+		return nil, fmt.Errorf("not supported drain mode %s", syncOptions.DrainMode)
+	}
+
+	// The importing clusters rely on the cluster id in metadata to group the instances.
+	if syncOptions.MCS && (syncOptions.ClusterID == "" || !syncOptions.IdentityMetadata) {
+		return nil, fmt.Errorf("multi-cluster services require the cluster id and identity metadata")
+	}
+
 	nacosClient, err := model.NewNacosClient(options)
 
 	if err != nil {
 		return nil, err
 	}
 
+	guard, err := model.NewDeregistrationGuard(syncOptions.Guard)
+	if err != nil {
+		return nil, err
+	}
+	nacosClient.SetDeregistrationGuard(guard)
+
 	c := &Controller{
-		nacosClient:      nacosClient,
-		watchedNamespace: watchedNamespace,
+		nacosClient:    nacosClient,
+		nacosNamespace: options.Namespace,
+		dynamicClient:  kubeClient.Dynamic(),
+		recorder:       model.NewEventRecorder(kubeClient.Kubernetes()),
+		kubeClient:     kubeClient,
+		syncOptions:    syncOptions,
+		kubeOptions:    kubeOptions,
+
+		namespaces:             make(map[string]*v1.Namespace),
+		pendingResyncs:         make(map[string]time.Time),
+		refusedUnregistrations: make(map[unregistrationKey]refusedUnregistration),
+		ruleErrors:             make(map[string]string),
+		podServiceKeys:         make(map[string]model.ServiceKey),
+		podServices:            make(map[model.ServiceKey]map[string]struct{}),
+		registeredPodServices:  make(map[model.ServiceKey]registeredPodService),
 	}
 
 	c.queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
-	// list and watch service
-	c.serviceInformer = kubeClient.InformerFactory().Core().V1().Services().Informer()
-	c.serviceLister = kubeClient.InformerFactory().Core().V1().Services().Lister()
-	registerHandlersForInformer(c.serviceInformer, c.queue, c.onServiceEvent)
-	// list and watch endpoints
-	c.endpointsInformer = kubeClient.InformerFactory().Core().V1().Endpoints().Informer()
-	c.endpointsLister = kubeClient.InformerFactory().Core().V1().Endpoints().Lister()
-	registerHandlersForInformer(c.endpointsInformer, c.queue, c.onEndpointsEvent)
+	// list and watch services, endpoints and pods restricted by the filters of services
+	scope, err := c.newInformerScope(kubeOptions)
+	if err != nil {
+		return nil, err
+	}
+	c.useScope(scope)
+	// list and watch nodes if the clusters come from topology, otherwise they are watched once
+	// a service registers the node port addresses
+	if syncOptions.ClusterFromTopology {
+		c.watchNodes()
+	}
+	// list and watch namespaces whose annotations and sync rules are inherited by services
+	c.namespaceInformer = kubeClient.InformerFactory().Core().V1().Namespaces().Informer()
+	c.namespaceLister = kubeClient.InformerFactory().Core().V1().Namespaces().Lister()
+	model.RegisterHandlersForInformer(c.namespaceInformer, c.queue, c.onNamespaceEvent)
+	// list and watch ingresses if enabled
+	if syncOptions.SyncIngress {
+		c.ingressInformer = kubeClient.InformerFactory().Networking().V1().Ingresses().Informer()
+		model.RegisterHandlersForInformer(c.ingressInformer, c.queue, c.onIngressEvent)
+	}
+	// list and watch http routes and gateways if enabled and served, otherwise the informers never
+	// sync and block the other resources.
+	if syncOptions.SyncHTTPRoute {
+		httpRouteResource, gatewayResource, err := model.DiscoverGatewayResources(kubeClient.Kubernetes().Discovery())
+		if err != nil {
+			logger.Warnf("Discover gateway api fail, http routes are not synced, err %v.", err)
+		} else {
+			httpRouteInformer := kubeClient.DynamicInformerFactory().ForResource(httpRouteResource)
+			c.httpRouteInformer = httpRouteInformer.Informer()
+			c.httpRouteLister = httpRouteInformer.Lister()
+			model.RegisterHandlersForInformer(c.httpRouteInformer, c.queue, c.onHTTPRouteEvent)
+
+			gatewayInformer := kubeClient.DynamicInformerFactory().ForResource(gatewayResource)
+			c.gatewayInformer = gatewayInformer.Informer()
+			c.gatewayLister = gatewayInformer.Lister()
+			model.RegisterHandlersForInformer(c.gatewayInformer, c.queue, c.onGatewayEvent)
+		}
+	}
+	// list and watch service exports if enabled
+	if syncOptions.MCS {
+		serviceExportInformer := kubeClient.DynamicInformerFactory().ForResource(model.ServiceExportResource)
+		c.serviceExportInformer = serviceExportInformer.Informer()
+		c.serviceExportLister = serviceExportInformer.Lister()
+		model.RegisterHandlersForInformer(c.serviceExportInformer, c.queue, c.onServiceExportEvent)
+	}
+	// list and watch NacosServiceSyncs if enabled
+	if syncOptions.ServiceSyncCRD {
+		c.serviceSyncInformer = kubeClient.DynamicInformerFactory().ForResource(model.NacosServiceSyncResource).Informer()
+		if err := c.serviceSyncInformer.AddIndexers(cache.Indexers{
+			model.NacosServiceSyncIndex: model.NacosServiceSyncIndexFunc,
+		}); err != nil {
+			return nil, err
+		}
+		model.RegisterHandlersForInformer(c.serviceSyncInformer, c.queue, c.onServiceSyncEvent)
+	}
+	// load and watch sync rules from nacos config if specified
+	if syncOptions.RulesDataID != "" {
+		rules, err := model.WatchSyncRules(options, syncOptions.RulesDataID, syncOptions.RulesGroup,
+			func(rules *model.SyncRules) {
+				c.queue.Add(&model.Task{
+					Handler: func() error {
+						return c.applySyncRules(rules)
+					},
+				})
+			})
+		if err != nil {
+			return nil, err
+		}
+		c.syncOptions.Rules = rules
+	}
 
 	return c, nil
 }
 
+// shouldServiceSync returns whether the service is annotated to be synced, exported or referenced by
+// NacosServiceSync.
+func (c *Controller) shouldServiceSync(service *v1.Service) bool {
+	shouldSync, _, _ := c.serviceInfoOf(service)
+	return shouldSync
+}
+
+// serviceInfoOf returns whether the service should be synced and its service info, which comes from
+// NacosServiceSync, ServiceExport, CEL rules and annotations in order of precedence. The service info
+// is only generated for the service which should be synced.
+func (c *Controller) serviceInfoOf(service *v1.Service) (bool, model.ServiceInfo, error) {
+	return c.serviceInfoWith(service, c.serviceSyncOf(service), c.isExported(service))
+}
+
+// serviceInfoWith is serviceInfoOf with the given NacosServiceSync and export. The namespace defaults
+// are inherited and the CEL rules are evaluated once, so that their errors are reported once.
+func (c *Controller) serviceInfoWith(service *v1.Service, serviceSync *model.NacosServiceSync,
+	exported bool) (bool, model.ServiceInfo, error) {
+	service = c.inheritNamespaceDefaults(service)
+	if !c.kubeOptions.ServiceWatched(service) || !c.syncOptions.Rules.Match(service) {
+		return false, model.ServiceInfo{}, nil
+	}
+
+	var result *model.CELResult
+	if serviceSync == nil && !exported {
+		result = c.evaluateRules(service)
+		if result != nil && !result.Sync || result == nil && !model.ShouldServiceSync(service) {
+			return false, model.ServiceInfo{}, nil
+		}
+	}
+
+	var serviceInfo model.ServiceInfo
+	var err error
+	if serviceSync != nil {
+		serviceInfo, err = model.GenerateServiceSyncInfo(service, serviceSync, c.syncOptions)
+	} else if exported {
+		// The exported service is always registered with the name and group of multi-cluster services.
+		serviceInfo, err = model.GenerateExportServiceInfo(service, c.syncOptions)
+	} else if result != nil {
+		serviceInfo, err = model.GenerateCELServiceInfo(service, result, c.syncOptions)
+	} else {
+		serviceInfo, err = model.GenerateServiceInfo(service, c.syncOptions)
+	}
+	if err != nil {
+		return true, model.ServiceInfo{}, err
+	}
+
+	// The namespace of syncer is always denoted by empty, so that the service key is unique.
+	if serviceInfo.Namespace == c.nacosNamespace {
+		serviceInfo.Namespace = ""
+	}
+	serviceInfo.Source = model.SourceOf(model.SourceKindService, service.Namespace, service.Name)
+	if c.syncOptions.IdentityMetadata {
+		serviceInfo.Identity = model.ServiceIdentity(service.Namespace, service.Name, c.syncOptions.ClusterID)
+	}
+	return true, serviceInfo, nil
+}
+
+// switchService syncs the service whose source of service info changed, and unregisters the service
+// generated from the previous source if it can not be updated in place.
+func (c *Controller) switchService(service *v1.Service, prevServiceSync *model.NacosServiceSync,
+	prevExported bool) error {
+	if prevShouldSync, prevServiceInfo, prevErr := c.serviceInfoWith(service, prevServiceSync, prevExported); prevShouldSync {
+		currShouldSync, currServiceInfo, currErr := c.serviceInfoOf(service)
+		if prevErr == nil && (!currShouldSync || (currErr == nil &&
+			(prevServiceInfo.ServiceKey != currServiceInfo.ServiceKey ||
+				prevServiceInfo.Ephemeral != currServiceInfo.Ephemeral))) {
+			c.unregisterService(service, prevServiceInfo)
+		}
+	}
+
+	return c.onServiceEvent(nil, service, model.EventAdd)
+}
+
+// resyncServicesOnChange applies the change of sync options, and syncs the services whose service
+// info is changed by it. The service generated before the change is unregistered if it can not be
+// updated in place.
+func (c *Controller) resyncServicesOnChange(services []*v1.Service, change func()) error {
+	type syncState struct {
+		serviceInfo model.ServiceInfo
+		err         error
+	}
+
+	stateOf := func(service *v1.Service) (syncState, bool) {
+		shouldSync, serviceInfo, err := c.serviceInfoOf(service)
+		return syncState{serviceInfo: serviceInfo, err: err}, shouldSync
+	}
+
+	prevStates := make(map[*v1.Service]syncState)
+	for _, service := range services {
+		if state, ok := stateOf(service); ok {
+			prevStates[service] = state
+		}
+	}
+
+	change()
+
+	var errs *multierror.Error
+	for _, service := range services {
+		prev, prevSynced := prevStates[service]
+		curr, currSynced := stateOf(service)
+		if prevSynced == currSynced && reflect.DeepEqual(prev, curr) {
+			continue
+		}
+
+		logger.Infof("Resync service (%s:%s) affected by the change.", service.Name, service.Namespace)
+		if prevSynced && prev.err == nil && (!currSynced || (curr.err == nil &&
+			(prev.serviceInfo.ServiceKey != curr.serviceInfo.ServiceKey ||
+				prev.serviceInfo.Ephemeral != curr.serviceInfo.Ephemeral))) {
+			c.unregisterService(service, prev.serviceInfo)
+		}
+		errs = multierror.Append(errs, c.onServiceEvent(nil, service, model.EventAdd))
+	}
+
+	return errs.ErrorOrNil()
+}
+
 func (c *Controller) buildAddresses(service *v1.Service, serviceInfo model.ServiceInfo) ([]model.Address, error) {
-	endpoints, err := c.endpointsLister.Endpoints(c.watchedNamespace).Get(service.Name)
+	var addresses []model.Address
+	var err error
+	if serviceInfo.AddressMode == model.AddressModePod {
+		addresses, err = c.buildPodAddresses(service, serviceInfo)
+	} else {
+		addresses, err = c.buildServiceAddresses(service, serviceInfo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if serviceInfo.ClusterName == "" && c.syncOptions.ClusterFromTopology {
+		c.fillClusterFromTopology(addresses)
+	}
+
+	if c.syncOptions.IdentityMetadata {
+		model.FillIdentityMetadata(service.Namespace, service.Name, c.syncOptions.ClusterID, addresses)
+	}
+
+	return addresses, nil
+}
+
+func (c *Controller) buildPodAddresses(service *v1.Service, serviceInfo model.ServiceInfo) ([]model.Address, error) {
+	endpoints, err := c.endpointsLister.Endpoints(service.Namespace).Get(service.Name)
 	if err != nil {
 		return nil, err
 	}
-	addresses := model.ConvertToAddresses(serviceInfo.Port, endpoints)
+	addresses := model.ConvertToAddresses(serviceInfo, endpoints)
+	c.fillInstanceInfo(service.Namespace, addresses)
+
+	if c.syncOptions.DrainGracePeriod > 0 {
+		draining, err := c.buildDrainingAddresses(service, serviceInfo, endpoints, addresses)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, draining...)
+	}
+
 	return addresses, nil
 }
 
+// resyncServiceAfter puts a task into queue to sync the service again after the given duration.
+// Nothing is put if the service is already going to be resynced before it, because the resync
+// puts the later one again if it is still required.
+func (c *Controller) resyncServiceAfter(namespace, name string, duration time.Duration) {
+	key := namespace + "/" + name
+	deadline := time.Now().Add(duration)
+	if pending, exist := c.pendingResyncs[key]; exist && !pending.After(deadline) {
+		return
+	}
+
+	c.pendingResyncs[key] = deadline
+	c.queue.AddAfter(&model.Task{
+		Handler: func() error {
+			if pending, exist := c.pendingResyncs[key]; exist && pending.Equal(deadline) {
+				delete(c.pendingResyncs, key)
+			}
+
+			service, err := c.serviceLister.Services(namespace).Get(name)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					return nil
+				}
+				return err
+			}
+			return c.onServiceEvent(nil, service, model.EventAdd)
+		},
+	}, duration)
+}
+
 func (c *Controller) onServiceEvent(old, curr interface{}, event model.Event) error {
 	currService, ok := curr.(*v1.Service)
 	if !ok {
 		return nil
 	}
+	if event == model.EventDelete {
+		defer delete(c.ruleErrors, currService.Namespace+"/"+currService.Name)
+	}
 
-	currShouldSync := model.ShouldServiceSync(currService)
+	currShouldSync, currServiceInfo, err := c.serviceInfoOf(currService)
 	if !currShouldSync && event != model.EventUpdate {
 		logger.Infof("Curr Service (%s:%s) should not be synced.", currService.Name, currService.Namespace)
 		return nil
 	}
 
-	currServiceInfo, err := model.GenerateServiceInfo(currService)
 	if err != nil {
 		logger.Errorf("Generate curr service info from service (%s:%s) fail.", currService.Name, currService.Namespace)
+		c.reportServiceSyncStatus(currService, currServiceInfo, err)
 		return nil
 	}
 
@@ -90,18 +440,19 @@ func (c *Controller) onServiceEvent(old, curr interface{}, event model.Event) er
 		addresses, err := c.buildAddresses(currService, currServiceInfo)
 		if err != nil {
 			logger.Errorf("Build addresses for curr service (%s:%s) fail, err %v", currServiceInfo.ServiceName, currServiceInfo.Group, err)
+			c.reportServiceSyncStatus(currService, currServiceInfo, err)
 			return err
 		}
-		c.nacosClient.RegisterService(currServiceInfo, addresses)
+		c.registerService(currService, currServiceInfo, addresses)
 	case model.EventDelete:
-		c.nacosClient.UnregisterService(currServiceInfo)
+		c.unregisterService(currService, currServiceInfo)
 	case model.EventUpdate:
 		oldService, ok := old.(*v1.Service)
 		if !ok {
 			return nil
 		}
 
-		oldServiceInfo, err := model.GenerateServiceInfo(oldService)
+		oldShouldSync, oldServiceInfo, err := c.serviceInfoOf(oldService)
 		if err != nil {
 			logger.Errorf("Generate old service info from service (%s:%s) fail.", oldService.Name, oldService.Namespace)
 			return nil
@@ -109,35 +460,60 @@ func (c *Controller) onServiceEvent(old, curr interface{}, event model.Event) er
 
 		// Old service should be synced, but now it changed to be not synced.
 		// We should Unregister old service.
-		if model.ShouldServiceSync(oldService) && !currShouldSync {
+		if oldShouldSync && !currShouldSync {
 			logger.Infof("Old service (%s:%s) should be unregistered.", oldServiceInfo.ServiceName, oldServiceInfo.Group)
-			c.nacosClient.UnregisterService(oldServiceInfo)
+			c.unregisterService(currService, oldServiceInfo)
+			return nil
+		}
+		if !currShouldSync {
 			return nil
 		}
 
 		addresses, err := c.buildAddresses(currService, currServiceInfo)
 		if err != nil {
 			logger.Errorf("Build addresses for curr service (%s:%s) fail, err %v", currServiceInfo.ServiceName, currServiceInfo.Group, err)
+			c.reportServiceSyncStatus(currService, currServiceInfo, err)
 			return err
 		}
 
-		// If the service key of old is not equal to new, it means that we get a new service and
-		// should to unregister old.
-		if oldServiceInfo.ServiceKey != currServiceInfo.ServiceKey {
+		if !oldShouldSync {
+			// The old service was not synced, so nothing is registered before.
+			c.registerService(currService, currServiceInfo, addresses)
+		} else if oldServiceInfo.ServiceKey != currServiceInfo.ServiceKey {
+			// If the service key of old is not equal to new, it means that we get a new service and
+			// should to unregister old.
 			// Register new service
-			c.nacosClient.RegisterService(currServiceInfo, addresses)
+			c.registerService(currService, currServiceInfo, addresses)
 			// Unregister old service
-			c.nacosClient.UnregisterService(oldServiceInfo)
-
-		} else if oldServiceInfo.Port != currServiceInfo.Port {
-			// If the port of old service is not equal to new, it means that we should push new addresses
-			// to nacos and remove old addresses which has old port.
-			c.nacosClient.RegisterService(currServiceInfo, addresses)
+			c.unregisterService(currService, oldServiceInfo)
+
+		} else if oldServiceInfo.Ephemeral != currServiceInfo.Ephemeral {
+			// The instance mode can not be changed in place, so we should unregister old instances
+			// and then register them again with the new mode.
+			c.unregisterService(currService, oldServiceInfo)
+			c.registerService(currService, currServiceInfo, addresses)
+		} else if oldServiceInfo.Port != currServiceInfo.Port ||
+			oldServiceInfo.NotReadyPolicy != currServiceInfo.NotReadyPolicy ||
+			oldServiceInfo.ClusterName != currServiceInfo.ClusterName ||
+			oldServiceInfo.AddressMode != currServiceInfo.AddressMode ||
+			currServiceInfo.AddressMode != model.AddressModePod ||
+			(currServiceInfo.AllowMassDeregistration && !oldServiceInfo.AllowMassDeregistration) {
+			// If the port, not ready policy, cluster or address mode of old service is not equal to new,
+			// it means that we should push new addresses to nacos and remove old addresses which has
+			// old port or state. The addresses of non pod mode come from service itself, so they are
+			// pushed on every change of service. The deregistration refused by guard is retried once
+			// it is allowed manually.
+			c.registerService(currService, currServiceInfo, addresses)
 		} else if !reflect.DeepEqual(oldServiceInfo.Metadata, currServiceInfo.Metadata) {
 			// If the metadata of old service is not equal to new, it means that we should republish new
 			// address.
 			c.nacosClient.RegisterServiceInstances(currServiceInfo, addresses)
 		}
+
+		// The settings of nacos service are independent of its instances.
+		if !reflect.DeepEqual(oldServiceInfo.Settings, currServiceInfo.Settings) {
+			c.nacosClient.UpdateServiceSettings(currServiceInfo)
+		}
 	}
 
 	return nil
@@ -154,20 +530,25 @@ func (c *Controller) onEndpointsEvent(old, curr interface{}, event model.Event)
 		return nil
 	}
 
-	service, err := c.serviceLister.Services(c.watchedNamespace).Get(endpoints.Name)
+	service, err := c.serviceLister.Services(endpoints.Namespace).Get(endpoints.Name)
+	// The service may be filtered out by the label selector while its endpoints are not.
+	if errors.IsNotFound(err) {
+		return nil
+	}
 	if err != nil || service == nil {
 		logger.Errorf("Get service (%s:%s) fail.", endpoints.Name, endpoints.Namespace)
 		return err
 	}
 
-	if !model.ShouldServiceSync(service) {
+	shouldSync, serviceInfo, err := c.serviceInfoOf(service)
+	if !shouldSync {
 		logger.Infof("Service (%s:%s) should not be synced.", service.Name, service.Namespace)
 		return nil
 	}
 
-	serviceInfo, err := model.GenerateServiceInfo(service)
 	if err != nil {
 		logger.Errorf("Generate service info from service (%s:%s) fail.", service.Name, service.Namespace)
+		c.reportServiceSyncStatus(service, serviceInfo, err)
 		return nil
 	}
 
@@ -175,42 +556,14 @@ func (c *Controller) onEndpointsEvent(old, curr interface{}, event model.Event)
 	addresses, err := c.buildAddresses(service, serviceInfo)
 	if err != nil {
 		logger.Errorf("Build addresses for service (%s:%s) fail, err %v", serviceInfo.ServiceName, serviceInfo.Group, err)
+		c.reportServiceSyncStatus(service, serviceInfo, err)
 		return err
 	}
-	c.nacosClient.RegisterService(serviceInfo, addresses)
+	c.registerService(service, serviceInfo, addresses)
 
 	return nil
 }
 
-func registerHandlersForInformer(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface,
-	handler func(interface{}, interface{}, model.Event) error) {
-
-	informer.AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				queue.Add(&model.Task{
-					Handler: func() error {
-						return handler(nil, obj, model.EventAdd)
-					},
-				})
-			},
-			UpdateFunc: func(old, cur interface{}) {
-				queue.Add(&model.Task{
-					Handler: func() error {
-						return handler(old, cur, model.EventUpdate)
-					},
-				})
-			},
-			DeleteFunc: func(obj interface{}) {
-				queue.Add(&model.Task{
-					Handler: func() error {
-						return handler(nil, obj, model.EventDelete)
-					},
-				})
-			},
-		})
-}
-
 func (c *Controller) syncAllServiceToNacos() error {
 	var err *multierror.Error
 
@@ -219,11 +572,63 @@ func (c *Controller) syncAllServiceToNacos() error {
 		err = multierror.Append(err, c.onServiceEvent(nil, service, model.EventAdd))
 	}
 
+	if c.syncOptions.SyncPod {
+		err = multierror.Append(err, c.syncAllPodServices())
+	}
+
+	if c.ingressInformer != nil {
+		for _, ingress := range c.ingressInformer.GetStore().List() {
+			err = multierror.Append(err, c.onIngressEvent(nil, ingress, model.EventAdd))
+		}
+	}
+
+	if c.httpRouteInformer != nil {
+		for _, route := range c.httpRouteInformer.GetStore().List() {
+			err = multierror.Append(err, c.onHTTPRouteEvent(nil, route, model.EventAdd))
+		}
+	}
+
+	c.queue.AddAfter(&model.Task{Handler: c.reconcileServiceSettings}, model.DefaultServiceSettingsResyncInterval)
+	// The persistent instances of the sources deleted while syncer was down are unregistered after
+	// the existing sources take over theirs.
+	c.queue.Add(&model.Task{Handler: c.unregisterOrphanedInstances})
+
 	return multierror.Flatten(err.ErrorOrNil())
 }
 
+// reconcileServiceSettings reverts the settings of nacos services changed outside syncer. It runs
+// periodically because nacos can not notify the change of them, and each round is bounded by a
+// timeout so that the events are not blocked by the slow nacos servers.
+func (c *Controller) reconcileServiceSettings() error {
+	defer c.queue.AddAfter(&model.Task{Handler: c.reconcileServiceSettings}, model.DefaultServiceSettingsResyncInterval)
+
+	c.nacosClient.ReconcileServiceSettings(model.DefaultServiceSettingsReconcileTimeout)
+	return nil
+}
+
 func (c *Controller) HasSynced() bool {
-	if !c.serviceInformer.HasSynced() || !c.endpointsInformer.HasSynced() {
+	if !c.serviceInformer.HasSynced() || !c.endpointsInformer.HasSynced() ||
+		!c.podInformer.HasSynced() || !c.namespaceInformer.HasSynced() {
+		return false
+	}
+
+	if c.nodeInformer != nil && !c.nodeInformer.HasSynced() {
+		return false
+	}
+
+	if c.ingressInformer != nil && !c.ingressInformer.HasSynced() {
+		return false
+	}
+
+	if c.httpRouteInformer != nil && (!c.httpRouteInformer.HasSynced() || !c.gatewayInformer.HasSynced()) {
+		return false
+	}
+
+	if c.serviceExportInformer != nil && !c.serviceExportInformer.HasSynced() {
+		return false
+	}
+
+	if c.serviceSyncInformer != nil && !c.serviceSyncInformer.HasSynced() {
 		return false
 	}
 
@@ -238,37 +643,13 @@ func (c *Controller) HasSynced() bool {
 	return true
 }
 
-func (c *Controller) processQueueTask() {
-	obj, shutdown := c.queue.Get()
-	defer c.queue.Done(obj)
-
-	if shutdown {
-		return
-	}
-
-	task, ok := obj.(*model.Task)
-	if !ok {
-		logger.Warn("Convert to task fail.")
-		return
-	}
-
-	if err := task.Handler(); err != nil {
-		if c.queue.NumRequeues(obj) < model.MaxRetry {
-			time.AfterFunc(model.DefaultTaskDelay, func() {
-				logger.Warnf("Task handle fail and put into queue again, err %v", err)
-				c.queue.AddRateLimited(obj)
-			})
-		} else {
-			logger.Warn("Task handle retry reach max times.")
-			c.queue.Forget(obj)
-		}
-	}
-}
-
 func (c *Controller) Run(stop <-chan struct{}) {
 	defer c.queue.ShutDown()
 
+	c.stop = stop
+	c.scope.start(stop)
+
 	cache.WaitForCacheSync(stop, c.HasSynced)
 
-	wait.Until(c.processQueueTask, 0, stop)
+	wait.Until(func() { model.ProcessQueueTask(c.queue) }, 0, stop)
 }
@@ -0,0 +1,113 @@
+package tonacos
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// buildDrainingAddresses returns the addresses of the terminating pods selected by the service,
+// which are still in the drain grace period.
+func (c *Controller) buildDrainingAddresses(service *v1.Service, serviceInfo model.ServiceInfo,
+	endpoints *v1.Endpoints, addresses []model.Address) ([]model.Address, error) {
+	if len(service.Spec.Selector) == 0 {
+		return nil, nil
+	}
+
+	pods, err := c.podLister.Pods(service.Namespace).List(labels.SelectorFromSet(service.Spec.Selector))
+	if err != nil {
+		return nil, err
+	}
+
+	existed := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		existed[address.IP] = struct{}{}
+	}
+
+	var draining []model.Address
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		if _, exist := existed[pod.Status.PodIP]; exist {
+			continue
+		}
+
+		// The endpoints may drop the running pod before its deletion is observed in the pod cache,
+		// so the instance is drained in advance rather than unregistered. The grace period starts
+		// once the deletion is observed.
+		terminating := pod.DeletionTimestamp != nil
+		if !terminating && (pod.Status.Phase != v1.PodRunning || endpointsContain(endpoints, pod.Status.PodIP)) {
+			continue
+		}
+
+		var remaining time.Duration
+		if terminating {
+			if remaining = time.Until(drainDeadline(pod, c.syncOptions.DrainGracePeriod)); remaining <= 0 {
+				continue
+			}
+		}
+
+		address := model.Address{
+			IP:          pod.Status.PodIP,
+			Port:        serviceInfo.Port,
+			Healthy:     true,
+			Enable:      c.syncOptions.DrainMode != model.DrainDisable,
+			Weight:      serviceInfo.InstanceWeight(),
+			Draining:    true,
+			ClusterName: serviceInfo.ClusterName,
+			NodeName:    pod.Spec.NodeName,
+			PodName:     pod.Name,
+		}
+		c.applyInstanceInfo(pod, &address)
+		if c.syncOptions.DrainMode == model.DrainWeight {
+			address.Weight = 0
+		}
+		draining = append(draining, address)
+
+		// Resync the service once the grace period expires, so that the drained instance
+		// can be unregistered.
+		if terminating {
+			c.resyncServiceAfter(service.Namespace, service.Name, remaining)
+		}
+	}
+
+	return draining, nil
+}
+
+// endpointsContain returns whether the ip is one of the ready or not ready addresses of endpoints.
+func endpointsContain(endpoints *v1.Endpoints, ip string) bool {
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.IP == ip {
+				return true
+			}
+		}
+		for _, address := range subset.NotReadyAddresses {
+			if address.IP == ip {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// drainStarted returns whether the deletion of pod is observed by the update, which starts the
+// grace period of its instances.
+func (c *Controller) drainStarted(old, curr *v1.Pod) bool {
+	return c.syncOptions.DrainGracePeriod > 0 && old.DeletionTimestamp == nil && curr.DeletionTimestamp != nil
+}
+
+// drainDeadline returns the time until which the instance of terminating pod is drained.
+func drainDeadline(pod *v1.Pod, gracePeriod time.Duration) time.Time {
+	deletionRequested := pod.DeletionTimestamp.Time
+	if pod.DeletionGracePeriodSeconds != nil {
+		deletionRequested = deletionRequested.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second)
+	}
+
+	return deletionRequested.Add(gracePeriod)
+}
@@ -0,0 +1,98 @@
+package tonacos
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// unregistrationKey identifies the instances of nacos service registered from a source.
+type unregistrationKey struct {
+	serviceKey model.ServiceKey
+
+	source string
+}
+
+// refusedUnregistration is the unregistration refused by guard, and the object is the one whose
+// events report the refusal.
+type refusedUnregistration struct {
+	obj runtime.Object
+
+	serviceInfo model.ServiceInfo
+}
+
+func unregistrationKeyOf(serviceInfo model.ServiceInfo) unregistrationKey {
+	return unregistrationKey{serviceKey: serviceInfo.ServiceKey, source: serviceInfo.Source}
+}
+
+// registerService registers the addresses of service, and reports the outcome to the status of its
+// NacosServiceSync. The deregistration refused by guard is reported as an event of the service too,
+// and the delayed one is retried after the delay.
+func (c *Controller) registerService(service *v1.Service, serviceInfo model.ServiceInfo, addresses []model.Address) {
+	err := c.registerObject(service, serviceInfo, addresses, func(retryAfter time.Duration) {
+		c.resyncServiceAfter(service.Namespace, service.Name, retryAfter)
+	})
+	c.reportServiceSyncStatus(service, serviceInfo, err)
+}
+
+// registerObject registers the addresses of service from the object, and gives up the unregistration
+// of the same source refused before. The deregistration refused by guard is reported as an event of
+// the object, and the delayed one is retried after the delay.
+func (c *Controller) registerObject(obj runtime.Object, serviceInfo model.ServiceInfo, addresses []model.Address,
+	retry func(retryAfter time.Duration)) error {
+	delete(c.refusedUnregistrations, unregistrationKeyOf(serviceInfo))
+	err := c.nacosClient.RegisterService(serviceInfo, addresses)
+	refused, ok := err.(*model.DeregistrationRefusedError)
+	if !ok {
+		return err
+	}
+
+	c.recorder.Eventf(obj, v1.EventTypeWarning, "DeregistrationRefused",
+		"%v, annotate %s=true to allow it", err, model.AllowMassDeregistrationKey())
+	if refused.RetryAfter > 0 {
+		retry(refused.RetryAfter)
+	}
+	return err
+}
+
+// unregisterService unregisters the service from the object. The unregistration refused by guard is
+// reported as an event of the object, and retried until it is allowed or the service is registered
+// from the same source again. The refusal by the cluster threshold is lifted when the window slides,
+// so it is retried after the window without delay.
+func (c *Controller) unregisterService(obj runtime.Object, serviceInfo model.ServiceInfo) {
+	key := unregistrationKeyOf(serviceInfo)
+	_, pending := c.refusedUnregistrations[key]
+	err := c.nacosClient.UnregisterService(serviceInfo)
+	refused, ok := err.(*model.DeregistrationRefusedError)
+	if !ok {
+		delete(c.refusedUnregistrations, key)
+		return
+	}
+
+	c.recorder.Eventf(obj, v1.EventTypeWarning, "DeregistrationRefused",
+		"%v, annotate %s=true to allow it", err, model.AllowMassDeregistrationKey())
+	c.refusedUnregistrations[key] = refusedUnregistration{obj: obj, serviceInfo: serviceInfo}
+	if pending {
+		return
+	}
+
+	retryAfter := refused.RetryAfter
+	if retryAfter == 0 {
+		retryAfter = model.DefaultDeregistrationWindow
+	}
+	c.queue.AddAfter(&model.Task{
+		Handler: func() error {
+			unregistration, exist := c.refusedUnregistrations[key]
+			if !exist {
+				return nil
+			}
+
+			delete(c.refusedUnregistrations, key)
+			c.unregisterService(unregistration.obj, unregistration.serviceInfo)
+			return nil
+		},
+	}, retryAfter)
+}
@@ -0,0 +1,115 @@
+package tonacos
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+func (c *Controller) onHTTPRouteEvent(old, curr interface{}, event model.Event) error {
+	route, ok := curr.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	var oldInfo *model.ServiceInfo
+	if oldRoute, ok := old.(*unstructured.Unstructured); ok && event == model.EventUpdate {
+		oldInfo = routeInfo(oldRoute, c.generateHTTPRouteInfo)
+	}
+
+	currInfo := routeInfo(route, c.generateHTTPRouteInfo)
+	if event == model.EventDelete {
+		if currInfo != nil {
+			c.unregisterService(route, *currInfo)
+		}
+		return nil
+	}
+
+	var addresses []model.Address
+	if currInfo != nil {
+		gateways, err := c.httpRouteGateways(route)
+		if err != nil {
+			return err
+		}
+		addresses = model.ConvertHTTPRouteToAddresses(*currInfo, route, gateways)
+		c.fillRouteIdentityMetadata(route, addresses)
+	}
+	c.registerRoute(route, oldInfo, currInfo, addresses, func(retryAfter time.Duration) {
+		c.resyncRouteAfter(c.httpRouteInformer, route, c.onHTTPRouteEvent, retryAfter)
+	})
+
+	return nil
+}
+
+func (c *Controller) generateHTTPRouteInfo(obj metav1.Object) (model.ServiceInfo, error) {
+	info, err := model.GenerateObjectInfo(obj, model.DefaultRoutePort, c.syncOptions)
+	if err != nil {
+		return model.ServiceInfo{}, err
+	}
+
+	c.setRouteSource(model.SourceKindHTTPRoute, obj, &info)
+	return info, nil
+}
+
+func (c *Controller) httpRouteGateways(route *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	var gateways []*unstructured.Unstructured
+	for _, key := range model.HTTPRouteParentGateways(route) {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		obj, err := c.gatewayLister.ByNamespace(namespace).Get(name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if gateway, ok := obj.(*unstructured.Unstructured); ok {
+			gateways = append(gateways, gateway)
+		}
+	}
+
+	return gateways, nil
+}
+
+func (c *Controller) onGatewayEvent(_, curr interface{}, _ model.Event) error {
+	gateway, ok := curr.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	routes, err := c.httpRouteLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	key := gateway.GetNamespace() + "/" + gateway.GetName()
+	var errs *multierror.Error
+	for _, obj := range routes {
+		route, ok := obj.(*unstructured.Unstructured)
+		if !ok || !model.ShouldObjectSync(route) {
+			continue
+		}
+
+		for _, parent := range model.HTTPRouteParentGateways(route) {
+			if parent == key {
+				logger.Infof("Gateway (%s) changed, resync http route (%s:%s).",
+					key, route.GetName(), route.GetNamespace())
+				errs = multierror.Append(errs, c.onHTTPRouteEvent(nil, route, model.EventAdd))
+				break
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
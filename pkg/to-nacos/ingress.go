@@ -0,0 +1,131 @@
+package tonacos
+
+import (
+	"reflect"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+func (c *Controller) onIngressEvent(old, curr interface{}, event model.Event) error {
+	ingress, ok := curr.(*networkingv1.Ingress)
+	if !ok {
+		return nil
+	}
+
+	var oldInfo *model.ServiceInfo
+	if oldIngress, ok := old.(*networkingv1.Ingress); ok && event == model.EventUpdate {
+		oldInfo = routeInfo(oldIngress, c.generateIngressInfo)
+	}
+
+	currInfo := routeInfo(ingress, c.generateIngressInfo)
+	if event == model.EventDelete {
+		if currInfo != nil {
+			c.unregisterService(ingress, *currInfo)
+		}
+		return nil
+	}
+
+	var addresses []model.Address
+	if currInfo != nil {
+		addresses = model.ConvertIngressToAddresses(*currInfo, ingress)
+		c.fillRouteIdentityMetadata(ingress, addresses)
+	}
+	c.registerRoute(ingress, oldInfo, currInfo, addresses, func(retryAfter time.Duration) {
+		c.resyncRouteAfter(c.ingressInformer, ingress, c.onIngressEvent, retryAfter)
+	})
+
+	return nil
+}
+
+func (c *Controller) generateIngressInfo(obj metav1.Object) (model.ServiceInfo, error) {
+	info, err := model.GenerateIngressInfo(obj.(*networkingv1.Ingress), c.syncOptions)
+	if err != nil {
+		return model.ServiceInfo{}, err
+	}
+
+	c.setRouteSource(model.SourceKindIngress, obj, &info)
+	return info, nil
+}
+
+// setRouteSource sets the source and identity of the instances of ingress or route.
+func (c *Controller) setRouteSource(kind string, obj metav1.Object, info *model.ServiceInfo) {
+	info.Source = model.SourceOf(kind, obj.GetNamespace(), obj.GetName())
+	if c.syncOptions.IdentityMetadata {
+		info.Identity = model.RouteIdentity(obj.GetNamespace(), obj.GetName(), c.syncOptions.ClusterID)
+	}
+}
+
+// routeInfo returns the service info of ingress or route, and returns nil if it should not be synced.
+func routeInfo(obj metav1.Object, generate func(metav1.Object) (model.ServiceInfo, error)) *model.ServiceInfo {
+	if !model.ShouldObjectSync(obj) {
+		return nil
+	}
+
+	info, err := generate(obj)
+	if err != nil {
+		logger.Errorf("Generate service info from (%s:%s) fail, err %v.", obj.GetName(), obj.GetNamespace(), err)
+		return nil
+	}
+
+	return &info
+}
+
+// registerRoute registers the addresses of ingress or route, and unregisters the old service if
+// it is replaced by the curr one. The deregistration refused by guard is retried after the delay.
+func (c *Controller) registerRoute(route runtime.Object, oldInfo, currInfo *model.ServiceInfo, addresses []model.Address,
+	retry func(retryAfter time.Duration)) {
+	if oldInfo != nil && (currInfo == nil || oldInfo.ServiceKey != currInfo.ServiceKey ||
+		oldInfo.Ephemeral != currInfo.Ephemeral) {
+		c.unregisterService(route, *oldInfo)
+		oldInfo = nil
+	}
+
+	if currInfo == nil {
+		return
+	}
+
+	if err := c.registerObject(route, *currInfo, addresses, retry); err != nil {
+		logger.Errorf("Register service (%s@@%s) from %s fail, err %v.",
+			currInfo.ServiceName, currInfo.Group, currInfo.Source, err)
+	}
+	if oldInfo != nil && !reflect.DeepEqual(oldInfo.Metadata, currInfo.Metadata) {
+		c.nacosClient.RegisterServiceInstances(*currInfo, addresses)
+	}
+}
+
+// resyncRouteAfter syncs the ingress or route again after the duration, if it still exists.
+func (c *Controller) resyncRouteAfter(informer cache.SharedIndexInformer, route runtime.Object,
+	handler func(old, curr interface{}, event model.Event) error, duration time.Duration) {
+	key, err := cache.MetaNamespaceKeyFunc(route)
+	if err != nil {
+		return
+	}
+
+	c.queue.AddAfter(&model.Task{
+		Handler: func() error {
+			curr, exist, err := informer.GetStore().GetByKey(key)
+			if err != nil || !exist {
+				return err
+			}
+			return handler(nil, curr, model.EventAdd)
+		},
+	}, duration)
+}
+
+func (c *Controller) fillRouteIdentityMetadata(obj metav1.Object, addresses []model.Address) {
+	if !c.syncOptions.IdentityMetadata {
+		return
+	}
+
+	model.FillIdentityMetadata(obj.GetNamespace(), "", c.syncOptions.ClusterID, addresses)
+	for i := range addresses {
+		addresses[i].Metadata[model.MetadataKubeRoute] = obj.GetName()
+	}
+}
@@ -0,0 +1,45 @@
+package tonacos
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// isExported returns whether the service is exported by ServiceExport of multi-cluster services api.
+func (c *Controller) isExported(service *v1.Service) bool {
+	if c.serviceExportLister == nil {
+		return false
+	}
+
+	_, err := c.serviceExportLister.ByNamespace(service.Namespace).Get(service.Name)
+	return err == nil
+}
+
+func (c *Controller) onServiceExportEvent(_, curr interface{}, event model.Event) error {
+	export, ok := curr.(*unstructured.Unstructured)
+	if !ok || event == model.EventUpdate {
+		return nil
+	}
+
+	service, err := c.serviceLister.Services(export.GetNamespace()).Get(export.GetName())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if event == model.EventDelete {
+		logger.Infof("Service (%s:%s) is not exported any more.", service.Name, service.Namespace)
+		// Register the service according to its annotations or NacosServiceSync again.
+		return c.switchService(service, c.serviceSyncOf(service), true)
+	}
+
+	// The exported service replaces the one registered according to annotations.
+	logger.Infof("Service (%s:%s) is exported.", service.Name, service.Namespace)
+	return c.switchService(service, c.serviceSyncOf(service), false)
+}
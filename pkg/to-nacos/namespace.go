@@ -0,0 +1,87 @@
+package tonacos
+
+import (
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// namespaceOf returns the namespace observed last, and nil if it is not found.
+func (c *Controller) namespaceOf(name string) *v1.Namespace {
+	if namespace, ok := c.namespaces[name]; ok {
+		return namespace
+	}
+
+	namespace, err := c.namespaceLister.Get(name)
+	if err != nil {
+		return nil
+	}
+	return namespace
+}
+
+// namespaceDefaultsOf returns the annotations of namespace inherited by the services in it.
+func (c *Controller) namespaceDefaultsOf(name string) map[string]string {
+	namespace := c.namespaceOf(name)
+	if namespace == nil {
+		return nil
+	}
+	return model.NamespaceDefaults(namespace)
+}
+
+// inheritNamespaceDefaults returns the service with the defaults of its namespace, and the invalid
+// defaults are reported as an event of the service and ignored.
+func (c *Controller) inheritNamespaceDefaults(service *v1.Service) *v1.Service {
+	inherited, err := model.InheritNamespaceDefaults(service, c.namespaceDefaultsOf(service.Namespace))
+	if err != nil {
+		logger.Errorf("Inherit namespace defaults for service (%s:%s) fail, err %v.", service.Name, service.Namespace, err)
+		c.recorder.Eventf(service, v1.EventTypeWarning, "NamespaceDefaultsError",
+			"Inherit namespace defaults fail, err %v", err)
+		return service
+	}
+
+	return inherited
+}
+
+func (c *Controller) onNamespaceEvent(_, curr interface{}, event model.Event) error {
+	namespace, ok := curr.(*v1.Namespace)
+	if !ok {
+		return nil
+	}
+
+	// The services are deleted along with the namespace.
+	if event == model.EventDelete {
+		delete(c.namespaces, namespace.Name)
+		return nil
+	}
+
+	if !c.namespaceChanged(c.namespaceOf(namespace.Name), namespace) {
+		c.namespaces[namespace.Name] = namespace
+		return nil
+	}
+
+	services, err := c.serviceLister.Services(namespace.Name).List(labels.Everything())
+	if err != nil {
+		logger.Errorf("List services of namespace %s fail, err %v.", namespace.Name, err)
+		return err
+	}
+
+	logger.Infof("Namespace %s changed, resync the affected services.", namespace.Name)
+	return c.resyncServicesOnChange(services, func() {
+		c.namespaces[namespace.Name] = namespace
+	})
+}
+
+// namespaceChanged returns whether the change of namespace may affect the services in it, which
+// inherit its defaults and evaluate the CEL rules against its labels and annotations.
+func (c *Controller) namespaceChanged(old, curr *v1.Namespace) bool {
+	if old == nil || !reflect.DeepEqual(model.NamespaceDefaults(old), model.NamespaceDefaults(curr)) {
+		return true
+	}
+
+	return c.syncOptions.Rules.HasCELRules() &&
+		(!reflect.DeepEqual(old.Labels, curr.Labels) || !reflect.DeepEqual(old.Annotations, curr.Annotations))
+}
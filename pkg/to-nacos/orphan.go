@@ -0,0 +1,113 @@
+package tonacos
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// unregisterOrphanedInstances unregisters the persistent instances registered before restart, whose
+// sources were deleted while syncer was down. Such instances never expire, and no event of their
+// sources is left to unregister them. The ones whose sources exist are taken over by the sources.
+func (c *Controller) unregisterOrphanedInstances() error {
+	// The sources of instances are only known from the identity metadata.
+	if !c.syncOptions.IdentityMetadata {
+		return nil
+	}
+
+	instances, err := c.nacosClient.OwnedPersistentInstances(c.syncOptions.ClusterID)
+	if err != nil {
+		logger.Errorf("List persistent instances registered before fail, err %v.", err)
+		return err
+	}
+
+	var errs *multierror.Error
+	var retryAfter time.Duration
+	for serviceKey, addresses := range instances {
+		var orphans []model.Address
+		for _, address := range addresses {
+			orphaned, err := c.isOrphaned(address)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			if orphaned {
+				orphans = append(orphans, address)
+			}
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+
+		if err := c.nacosClient.UnregisterOrphanedInstances(serviceKey, orphans); err != nil {
+			refused, ok := err.(*model.DeregistrationRefusedError)
+			if !ok {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			// The refused ones are checked again once the guard may allow them.
+			delay := refused.RetryAfter
+			if delay <= 0 {
+				delay = model.DefaultDeregistrationWindow
+			}
+			if retryAfter == 0 || delay < retryAfter {
+				retryAfter = delay
+			}
+		}
+	}
+	if retryAfter > 0 {
+		c.queue.AddAfter(&model.Task{Handler: c.unregisterOrphanedInstances}, retryAfter)
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// isOrphaned returns whether the source of the instance no longer exists. The instances of the
+// namespaces not watched, or of the kinds not synced, may be owned by the other syncers.
+func (c *Controller) isOrphaned(address model.Address) (bool, error) {
+	namespace := address.Metadata[model.MetadataKubeNamespace]
+	if namespace == "" || !c.kubeOptions.NamespaceWatched(namespace) {
+		return false, nil
+	}
+
+	var err error
+	switch service, route, pod := address.Metadata[model.MetadataKubeService], address.Metadata[model.MetadataKubeRoute],
+		address.Metadata[model.MetadataKubePod]; {
+	case service != "":
+		// The service out of the filters is not cached, so it is read from the api server.
+		_, err = c.kubeClient.Kubernetes().CoreV1().Services(namespace).Get(context.TODO(), service, metav1.GetOptions{})
+	case route != "":
+		if c.ingressInformer == nil && c.httpRouteLister == nil {
+			return false, nil
+		}
+		if c.ingressInformer != nil {
+			if _, exist, _ := c.ingressInformer.GetStore().GetByKey(namespace + "/" + route); exist {
+				return false, nil
+			}
+		}
+		if c.httpRouteLister != nil {
+			if _, err := c.httpRouteLister.ByNamespace(namespace).Get(route); err == nil {
+				return false, nil
+			}
+		}
+		return true, nil
+	case pod != "":
+		if !c.syncOptions.SyncPod {
+			return false, nil
+		}
+		_, err = c.podLister.Pods(namespace).Get(pod)
+	default:
+		return false, nil
+	}
+
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,113 @@
+package tonacos
+
+import (
+	"reflect"
+
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// fillInstanceInfo resolves the pods of addresses and sets the weight and metadata of
+// instances according to them.
+func (c *Controller) fillInstanceInfo(namespace string, addresses []model.Address) {
+	for i := range addresses {
+		if addresses[i].PodName == "" {
+			continue
+		}
+
+		pod, err := c.podLister.Pods(namespace).Get(addresses[i].PodName)
+		if err != nil {
+			logger.Warnf("Get pod (%s:%s) fail, err %v.", addresses[i].PodName, namespace, err)
+			continue
+		}
+		c.applyInstanceInfo(pod, &addresses[i])
+	}
+}
+
+func (c *Controller) applyInstanceInfo(pod *v1.Pod, address *model.Address) {
+	info, err := model.GenerateInstanceInfo(pod, address.Weight, c.syncOptions)
+	if err != nil {
+		logger.Errorf("Generate instance info from pod (%s:%s) fail, err %v.", pod.Name, pod.Namespace, err)
+		return
+	}
+
+	address.Weight = info.Weight
+	address.Metadata = info.Metadata
+}
+
+func (c *Controller) onPodEvent(old, curr interface{}, event model.Event) error {
+	var errs *multierror.Error
+	if c.syncOptions.SyncPod {
+		errs = multierror.Append(errs, c.syncPodServices(old, curr, event))
+	}
+	errs = multierror.Append(errs, c.resyncServicesOnPodEvent(old, curr, event))
+
+	return errs.ErrorOrNil()
+}
+
+// resyncServicesOnPodEvent syncs the services selecting the pod, if the change of pod is not
+// reflected by endpoints.
+func (c *Controller) resyncServicesOnPodEvent(old, curr interface{}, event model.Event) error {
+	pod, ok := curr.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+
+	switch event {
+	case model.EventAdd:
+		// The new pod is reflected by endpoints.
+		return nil
+	case model.EventDelete:
+		// Only the deletion of pod should unregister the drained instances in advance.
+		if c.syncOptions.DrainGracePeriod == 0 {
+			return nil
+		}
+	case model.EventUpdate:
+		oldPod, ok := old.(*v1.Pod)
+		if !ok || (!c.instanceInfoChanged(oldPod, pod) && !c.drainStarted(oldPod, pod)) {
+			return nil
+		}
+	}
+
+	return c.resyncServicesOfPod(pod)
+}
+
+// instanceInfoChanged returns whether the weight or metadata of instance extracted from pod changed.
+func (c *Controller) instanceInfoChanged(old, curr *v1.Pod) bool {
+	oldInfo, oldErr := model.GenerateInstanceInfo(old, model.DefaultNacosEndpointWeight, c.syncOptions)
+	currInfo, currErr := model.GenerateInstanceInfo(curr, model.DefaultNacosEndpointWeight, c.syncOptions)
+	if oldErr != nil || currErr != nil {
+		// The instance info is changed if only one of them is valid.
+		return (oldErr == nil) != (currErr == nil)
+	}
+
+	return !reflect.DeepEqual(oldInfo, currInfo)
+}
+
+// resyncServicesOfPod syncs all the services which select the pod.
+func (c *Controller) resyncServicesOfPod(pod *v1.Pod) error {
+	services, err := c.serviceLister.Services(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, service := range services {
+		if len(service.Spec.Selector) == 0 || !c.shouldServiceSync(service) {
+			continue
+		}
+		if !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		logger.Infof("Pod (%s:%s) changed, resync service (%s:%s).",
+			pod.Name, pod.Namespace, service.Name, service.Namespace)
+		errs = multierror.Append(errs, c.onServiceEvent(nil, service, model.EventAdd))
+	}
+
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,220 @@
+package tonacos
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// registeredPodService is the nacos service registered from pods, and the pod is the one whose events
+// report the refused unregistration.
+type registeredPodService struct {
+	pod *v1.Pod
+
+	serviceInfo model.ServiceInfo
+}
+
+// syncPodServices syncs the services which the old and curr pod belong to, when the pods
+// are synced without k8s service.
+func (c *Controller) syncPodServices(_, curr interface{}, event model.Event) error {
+	pod, ok := curr.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+
+	podKey := pod.Namespace + "/" + pod.Name
+	prevKey, prevSynced := c.podServiceKeys[podKey]
+	if event == model.EventDelete {
+		c.unindexPod(podKey)
+	} else {
+		c.indexPod(pod)
+	}
+	currKey, currSynced := c.podServiceKeys[podKey]
+
+	var errs *multierror.Error
+	if prevSynced && (!currSynced || prevKey != currKey) {
+		errs = multierror.Append(errs, c.syncPodService(prevKey))
+	}
+	if currSynced {
+		errs = multierror.Append(errs, c.syncPodService(currKey))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// podServiceKey returns the key of nacos service which the pod is synced to, and returns false if
+// the pod should not be synced.
+func (c *Controller) podServiceKey(pod *v1.Pod) (model.ServiceKey, bool) {
+	serviceKey, ok := model.PodServiceKeyOf(pod, c.syncOptions)
+	// The namespace of syncer is always denoted by empty, so that the service key is unique.
+	if ok && serviceKey.Namespace == c.nacosNamespace {
+		serviceKey.Namespace = ""
+	}
+	return serviceKey, ok
+}
+
+// indexPod groups the pod by the key of nacos service which it is synced to.
+func (c *Controller) indexPod(pod *v1.Pod) {
+	podKey := pod.Namespace + "/" + pod.Name
+	c.unindexPod(podKey)
+	serviceKey, ok := c.podServiceKey(pod)
+	if !ok {
+		return
+	}
+
+	c.podServiceKeys[podKey] = serviceKey
+	if c.podServices[serviceKey] == nil {
+		c.podServices[serviceKey] = make(map[string]struct{})
+	}
+	c.podServices[serviceKey][podKey] = struct{}{}
+}
+
+func (c *Controller) unindexPod(podKey string) {
+	serviceKey, ok := c.podServiceKeys[podKey]
+	if !ok {
+		return
+	}
+
+	delete(c.podServiceKeys, podKey)
+	delete(c.podServices[serviceKey], podKey)
+	if len(c.podServices[serviceKey]) == 0 {
+		delete(c.podServices, serviceKey)
+	}
+}
+
+// syncPodService registers all the pods which are synced to the nacos service. If there is no such
+// pod any more, the service is unregistered.
+func (c *Controller) syncPodService(serviceKey model.ServiceKey) error {
+	pods := make([]*v1.Pod, 0, len(c.podServices[serviceKey]))
+	for podKey := range c.podServices[serviceKey] {
+		namespace, name, err := cache.SplitMetaNamespaceKey(podKey)
+		if err != nil {
+			continue
+		}
+		// The pod changed after being indexed is synced by its pending event.
+		pod, err := c.podLister.Pods(namespace).Get(name)
+		if err != nil {
+			continue
+		}
+		if key, ok := c.podServiceKey(pod); ok && key == serviceKey {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		if registered, exist := c.podServices[serviceKey]; exist && len(registered) > 0 {
+			return nil
+		}
+		if registered, exist := c.registeredPodServices[serviceKey]; exist {
+			delete(c.registeredPodServices, serviceKey)
+			c.unregisterService(registered.pod, registered.serviceInfo)
+		}
+		return nil
+	}
+
+	// The service level info, such as ephemeral, comes from the first pod so that it is stable.
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	serviceInfo, err := c.generatePodServiceInfo(pods[0])
+	if err != nil {
+		logger.Errorf("Generate service info from pod (%s:%s) fail, err %v.", pods[0].Name, pods[0].Namespace, err)
+		return nil
+	}
+
+	var addresses []model.Address
+	for _, p := range pods {
+		podInfo, err := model.GeneratePodServiceInfo(p, c.syncOptions)
+		if err != nil {
+			logger.Errorf("Generate service info from pod (%s:%s) fail, err %v.", p.Name, p.Namespace, err)
+			continue
+		}
+
+		address, ok := model.ConvertPodToAddress(podInfo, p)
+		if !ok {
+			continue
+		}
+
+		// The metadata of pod is the metadata of its own instance.
+		metadata := podInfo.Metadata
+		c.applyInstanceInfo(p, &address)
+		address.Metadata = model.MergeMetadata(metadata, address.Metadata)
+
+		single := []model.Address{address}
+		if address.ClusterName == "" && c.syncOptions.ClusterFromTopology {
+			c.fillClusterFromTopology(single)
+		}
+		if c.syncOptions.IdentityMetadata {
+			model.FillIdentityMetadata(p.Namespace, "", c.syncOptions.ClusterID, single)
+		}
+		addresses = append(addresses, single[0])
+	}
+
+	// The metadata of each pod is carried by its address.
+	serviceInfo.Metadata = nil
+	c.registeredPodServices[serviceKey] = registeredPodService{pod: pods[0], serviceInfo: serviceInfo}
+	if err := c.registerObject(pods[0], serviceInfo, addresses, func(retryAfter time.Duration) {
+		c.queue.AddAfter(&model.Task{Handler: func() error { return c.syncPodService(serviceKey) }}, retryAfter)
+	}); err != nil {
+		logger.Errorf("Register service (%s@@%s) from pods fail, err %v.", serviceInfo.ServiceName, serviceInfo.Group, err)
+	}
+
+	return nil
+}
+
+// generatePodServiceInfo generates the service info from pod, whose instances come from all the
+// pods with the same service key.
+func (c *Controller) generatePodServiceInfo(pod *v1.Pod) (model.ServiceInfo, error) {
+	serviceInfo, err := model.GeneratePodServiceInfo(pod, c.syncOptions)
+	if err != nil {
+		return model.ServiceInfo{}, err
+	}
+
+	// The namespace of syncer is always denoted by empty, so that the service key is unique.
+	if serviceInfo.Namespace == c.nacosNamespace {
+		serviceInfo.Namespace = ""
+	}
+	serviceInfo.Source = model.SourceKindPod
+	if c.syncOptions.IdentityMetadata {
+		serviceInfo.Identity = model.PodIdentity(c.syncOptions.ClusterID)
+	}
+	return serviceInfo, nil
+}
+
+// syncAllPodServices groups all the pods by the keys of nacos services again, because the templates
+// and rules generating them may change, and syncs the services of them.
+func (c *Controller) syncAllPodServices() error {
+	pods, err := c.podLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	serviceKeys := make(map[model.ServiceKey]struct{}, len(c.registeredPodServices))
+	for serviceKey := range c.registeredPodServices {
+		serviceKeys[serviceKey] = struct{}{}
+	}
+	c.podServiceKeys = make(map[string]model.ServiceKey)
+	c.podServices = make(map[model.ServiceKey]map[string]struct{})
+	for _, pod := range pods {
+		c.indexPod(pod)
+	}
+	for serviceKey := range c.podServices {
+		serviceKeys[serviceKey] = struct{}{}
+	}
+
+	var errs *multierror.Error
+	for serviceKey := range serviceKeys {
+		errs = multierror.Append(errs, c.syncPodService(serviceKey))
+	}
+
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,60 @@
+package tonacos
+
+import (
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// evaluateRules evaluates the CEL rules against the service, and the error is reported as an event
+// of the service once until it changes. It returns nil if no rule selects the service.
+func (c *Controller) evaluateRules(service *v1.Service) *model.CELResult {
+	if !c.syncOptions.Rules.HasCELRules() {
+		return nil
+	}
+
+	namespace := c.namespaceOf(service.Namespace)
+	endpoints, err := c.endpointsLister.Endpoints(service.Namespace).Get(service.Name)
+	if err != nil {
+		endpoints = nil
+	}
+
+	key := service.Namespace + "/" + service.Name
+	result, err := c.syncOptions.Rules.EvaluateCEL(service, namespace, endpoints)
+	if err != nil {
+		// The error names the rule, so the same rule failing on the same service is reported once.
+		if c.ruleErrors[key] != err.Error() {
+			c.ruleErrors[key] = err.Error()
+			logger.Errorf("Evaluate sync rules for service (%s:%s) fail, err %v.", service.Name, service.Namespace, err)
+			c.recorder.Eventf(service, v1.EventTypeWarning, "SyncRuleError", "Evaluate sync rules fail, err %v", err)
+		}
+		return nil
+	}
+	delete(c.ruleErrors, key)
+
+	return result
+}
+
+// applySyncRules replaces the sync rules, and syncs the services affected by the change.
+func (c *Controller) applySyncRules(rules *model.SyncRules) error {
+	var services []*v1.Service
+	for _, obj := range c.serviceInformer.GetStore().List() {
+		if service, ok := obj.(*v1.Service); ok {
+			services = append(services, service)
+		}
+	}
+
+	logger.Info("Sync rules changed, resync the affected services.")
+	err := c.resyncServicesOnChange(services, func() {
+		c.syncOptions.Rules = rules
+		// The errors of the previous rules are not relevant any more.
+		c.ruleErrors = make(map[string]string)
+	})
+	if c.syncOptions.SyncPod {
+		// The pods are grouped by the service keys generated with the rules.
+		err = multierror.Append(err, c.syncAllPodServices()).ErrorOrNil()
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package tonacos
+
+import (
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// informerScope are the informers of services, endpoints and pods restricted by the filters of
+// services. They are replaced as a whole when the filters change at runtime.
+type informerScope struct {
+	factories model.ScopedInformerFactories
+
+	serviceInformer cache.SharedIndexInformer
+	serviceLister   lister.ServiceLister
+
+	endpointsInformer cache.SharedIndexInformer
+	endpointsLister   lister.EndpointsLister
+
+	podInformer cache.SharedIndexInformer
+	podLister   lister.PodLister
+
+	// stop stops the informers of the scope only.
+	stop chan struct{}
+	once sync.Once
+}
+
+// newInformerScope creates the informers restricted by the filters of options. Only the events of
+// the scope in use are handled.
+func (c *Controller) newInformerScope(options model.KubeOptions) (*informerScope, error) {
+	factories, err := model.NewScopedInformerFactories(c.kubeClient.Kubernetes(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &informerScope{factories: factories, stop: make(chan struct{})}
+	// list and watch service
+	scope.serviceInformer = factories.Service.Core().V1().Services().Informer()
+	scope.serviceLister = factories.Service.Core().V1().Services().Lister()
+	c.registerScopeHandlers(scope, scope.serviceInformer, c.onServiceEvent)
+	// list and watch endpoints
+	scope.endpointsInformer = factories.Service.Core().V1().Endpoints().Informer()
+	scope.endpointsLister = factories.Service.Core().V1().Endpoints().Lister()
+	c.registerScopeHandlers(scope, scope.endpointsInformer, c.onEndpointsEvent)
+	// list and watch pods in the namespaces of services
+	scope.podInformer = factories.Pod.Core().V1().Pods().Informer()
+	scope.podLister = factories.Pod.Core().V1().Pods().Lister()
+	c.registerScopeHandlers(scope, scope.podInformer, c.onPodEvent)
+
+	return scope, nil
+}
+
+// registerScopeHandlers registers the handler which ignores the events after the scope is replaced,
+// because the handlers can not be removed from informers.
+func (c *Controller) registerScopeHandlers(scope *informerScope, informer cache.SharedIndexInformer,
+	handler func(interface{}, interface{}, model.Event) error) {
+	model.RegisterHandlersForInformer(informer, c.queue, func(old, curr interface{}, event model.Event) error {
+		if c.scope != scope {
+			return nil
+		}
+		return handler(old, curr, event)
+	})
+}
+
+// useScope makes the informers of scope the ones in use.
+func (c *Controller) useScope(scope *informerScope) {
+	c.scope = scope
+	c.serviceInformer, c.serviceLister = scope.serviceInformer, scope.serviceLister
+	c.endpointsInformer, c.endpointsLister = scope.endpointsInformer, scope.endpointsLister
+	c.podInformer, c.podLister = scope.podInformer, scope.podLister
+}
+
+// start runs the informers of scope until either the scope or the controller is stopped.
+func (s *informerScope) start(stop <-chan struct{}) {
+	s.factories.Start(s.stop)
+	go func() {
+		select {
+		case <-stop:
+			s.close()
+		case <-s.stop:
+		}
+	}()
+}
+
+func (s *informerScope) hasSynced() bool {
+	return s.serviceInformer.HasSynced() && s.endpointsInformer.HasSynced() && s.podInformer.HasSynced()
+}
+
+func (s *informerScope) close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// ReloadKubeOptions applies the filters of services changed at runtime. The informers are rebuilt
+// with the filters, because they restrict the lists and watches.
+func (c *Controller) ReloadKubeOptions(options model.KubeOptions) {
+	c.queue.Add(&model.Task{
+		Handler: func() error {
+			return c.applyKubeOptions(c.kubeOptions.WithFilters(options))
+		},
+	})
+}
+
+// applyKubeOptions replaces the filters of services, and syncs the services which are watched
+// before or after the change.
+func (c *Controller) applyKubeOptions(options model.KubeOptions) error {
+	if options.LabelSelector == c.kubeOptions.LabelSelector &&
+		sameStrings(options.IncludeNamespaces, c.kubeOptions.IncludeNamespaces) &&
+		sameStrings(options.ExcludeNamespaces, c.kubeOptions.ExcludeNamespaces) {
+		return nil
+	}
+
+	scope, err := c.newInformerScope(options)
+	if err != nil {
+		// The previous filters are kept until the invalid ones are fixed.
+		logger.Errorf("Reload the filters of services fail, err %v.", err)
+		return nil
+	}
+	scope.start(c.stop)
+	if !cache.WaitForCacheSync(scope.stop, scope.hasSynced) {
+		return nil
+	}
+
+	// The services which are not watched any more are unregistered, and the ones which are watched
+	// now are registered.
+	services := make(map[string]*v1.Service)
+	for _, store := range []cache.Store{c.serviceInformer.GetStore(), scope.serviceInformer.GetStore()} {
+		for _, obj := range store.List() {
+			if service, ok := obj.(*v1.Service); ok {
+				services[service.Namespace+"/"+service.Name] = service
+			}
+		}
+	}
+	list := make([]*v1.Service, 0, len(services))
+	for _, service := range services {
+		list = append(list, service)
+	}
+
+	logger.Info("Filters of services changed, resync the affected services.")
+	prev := c.scope
+	err = c.resyncServicesOnChange(list, func() {
+		c.kubeOptions = options
+		c.useScope(scope)
+	})
+	prev.close()
+	if c.syncOptions.SyncPod {
+		// The pods out of the namespaces watched now are gone with the previous informers.
+		err = multierror.Append(err, c.syncAllPodServices()).ErrorOrNil()
+	}
+	return err
+}
+
+// sameStrings returns whether the lists are the same, and nil is the same as empty.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
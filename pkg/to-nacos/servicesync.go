@@ -0,0 +1,198 @@
+package tonacos
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// serviceSyncOf returns the NacosServiceSync which references the service. If there are several ones,
+// the first one by name takes effect.
+func (c *Controller) serviceSyncOf(service *v1.Service) *model.NacosServiceSync {
+	serviceSyncs := c.serviceSyncsOf(service)
+	if len(serviceSyncs) == 0 {
+		return nil
+	}
+
+	return serviceSyncs[0]
+}
+
+// serviceSyncsOf returns all the NacosServiceSyncs which reference the service, sorted by name.
+func (c *Controller) serviceSyncsOf(service *v1.Service) []*model.NacosServiceSync {
+	if c.serviceSyncInformer == nil {
+		return nil
+	}
+
+	objs, err := c.serviceSyncInformer.GetIndexer().ByIndex(model.NacosServiceSyncIndex,
+		service.Namespace+"/"+service.Name)
+	if err != nil {
+		return nil
+	}
+
+	var serviceSyncs []*model.NacosServiceSync
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		serviceSync, err := model.ParseNacosServiceSync(u)
+		if err != nil {
+			logger.Errorf("Parse NacosServiceSync (%s:%s) fail, err %v.", u.GetName(), u.GetNamespace(), err)
+			continue
+		}
+		serviceSyncs = append(serviceSyncs, serviceSync)
+	}
+
+	sortServiceSyncs(serviceSyncs)
+	return serviceSyncs
+}
+
+func sortServiceSyncs(serviceSyncs []*model.NacosServiceSync) {
+	sort.Slice(serviceSyncs, func(i, j int) bool { return serviceSyncs[i].Name < serviceSyncs[j].Name })
+}
+
+func (c *Controller) onServiceSyncEvent(old, curr interface{}, event model.Event) error {
+	u, ok := curr.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	serviceSync, err := model.ParseNacosServiceSync(u)
+	if err != nil {
+		logger.Errorf("Parse NacosServiceSync (%s:%s) fail, err %v.", u.GetName(), u.GetNamespace(), err)
+		return nil
+	}
+
+	switch event {
+	case model.EventAdd:
+		err = c.switchServiceSync(serviceSync.Namespace, serviceSync.Spec.ServiceRef.Name, nil, serviceSync)
+	case model.EventDelete:
+		return c.switchServiceSync(serviceSync.Namespace, serviceSync.Spec.ServiceRef.Name, serviceSync, nil)
+	case model.EventUpdate:
+		oldObj, ok := old.(*unstructured.Unstructured)
+		if !ok || oldObj.GetGeneration() == u.GetGeneration() {
+			// Only the status or metadata changed.
+			return nil
+		}
+		var oldServiceSync *model.NacosServiceSync
+		if oldServiceSync, err = model.ParseNacosServiceSync(oldObj); err != nil {
+			return nil
+		}
+
+		if oldServiceSync.Spec.ServiceRef == serviceSync.Spec.ServiceRef {
+			err = c.switchServiceSync(serviceSync.Namespace, serviceSync.Spec.ServiceRef.Name,
+				oldServiceSync, serviceSync)
+		} else {
+			// The previous service is not referenced any more.
+			err = c.switchServiceSync(oldServiceSync.Namespace, oldServiceSync.Spec.ServiceRef.Name,
+				oldServiceSync, nil)
+			if err == nil {
+				err = c.switchServiceSync(serviceSync.Namespace, serviceSync.Spec.ServiceRef.Name,
+					nil, serviceSync)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// The status of the effective one is reported after syncing the service.
+	service, err := c.serviceLister.Services(serviceSync.Namespace).Get(serviceSync.Spec.ServiceRef.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.updateServiceSyncStatus(serviceSync, model.NacosServiceSyncStatus{
+				Phase:   model.ServiceSyncFailed,
+				Message: fmt.Sprintf("service %s is not found", serviceSync.Spec.ServiceRef.Name),
+			})
+			return nil
+		}
+		return err
+	}
+	if effective := c.serviceSyncOf(service); effective != nil && effective.Name != serviceSync.Name {
+		c.updateServiceSyncStatus(serviceSync, model.NacosServiceSyncStatus{
+			Phase:   model.ServiceSyncFailed,
+			Message: fmt.Sprintf("service %s is already synced by %s", service.Name, effective.Name),
+		})
+	}
+
+	return nil
+}
+
+// switchServiceSync syncs the service whose NacosServiceSyncs changed. The previous NacosServiceSyncs
+// before the event are the current ones except the added one, plus the removed one.
+func (c *Controller) switchServiceSync(namespace, name string, removed, added *model.NacosServiceSync) error {
+	service, err := c.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	var prev []*model.NacosServiceSync
+	for _, serviceSync := range c.serviceSyncsOf(service) {
+		if (added == nil || serviceSync.Name != added.Name) && (removed == nil || serviceSync.Name != removed.Name) {
+			prev = append(prev, serviceSync)
+		}
+	}
+	if removed != nil {
+		prev = append(prev, removed)
+	}
+	sortServiceSyncs(prev)
+
+	var prevServiceSync *model.NacosServiceSync
+	if len(prev) > 0 {
+		prevServiceSync = prev[0]
+	}
+
+	return c.switchService(service, prevServiceSync, c.isExported(service))
+}
+
+// reportServiceSyncStatus updates the status of the NacosServiceSync referencing the service with
+// the outcome of sync.
+func (c *Controller) reportServiceSyncStatus(service *v1.Service, serviceInfo model.ServiceInfo, err error) {
+	serviceSync := c.serviceSyncOf(service)
+	if serviceSync == nil {
+		return
+	}
+
+	status := model.NacosServiceSyncStatus{
+		Phase:       model.ServiceSyncSynced,
+		ServiceName: serviceInfo.ServiceName,
+		Group:       serviceInfo.Group,
+	}
+	if err != nil {
+		status = model.NacosServiceSyncStatus{
+			Phase:   model.ServiceSyncFailed,
+			Message: err.Error(),
+		}
+	}
+	c.updateServiceSyncStatus(serviceSync, status)
+}
+
+func (c *Controller) updateServiceSyncStatus(serviceSync *model.NacosServiceSync, status model.NacosServiceSyncStatus) {
+	status.ObservedGeneration = serviceSync.Generation
+	if reflect.DeepEqual(serviceSync.Status, status) {
+		return
+	}
+
+	serviceSync.Status = status
+	obj, err := serviceSync.ToUnstructured()
+	if err == nil {
+		_, err = c.dynamicClient.Resource(model.NacosServiceSyncResource).Namespace(serviceSync.Namespace).
+			UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		logger.Errorf("Update status of NacosServiceSync (%s:%s) fail, err %v.",
+			serviceSync.Name, serviceSync.Namespace, err)
+	}
+}
@@ -0,0 +1,46 @@
+package tonacos
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// fillClusterFromTopology sets the nacos cluster of addresses to the topology zone of
+// the nodes where the pods are running.
+func (c *Controller) fillClusterFromTopology(addresses []model.Address) {
+	zones := make(map[string]string)
+	for i := range addresses {
+		nodeName := addresses[i].NodeName
+		if nodeName == "" {
+			continue
+		}
+
+		zone, exist := zones[nodeName]
+		if !exist {
+			zone = c.nodeZone(nodeName)
+			zones[nodeName] = zone
+		}
+		addresses[i].ClusterName = zone
+	}
+}
+
+func (c *Controller) nodeZone(nodeName string) string {
+	node, err := c.nodeLister.Get(nodeName)
+	if err != nil {
+		logger.Warnf("Get node (%s) fail, err %v.", nodeName, err)
+		return ""
+	}
+
+	return zoneOf(node)
+}
+
+// zoneOf returns the topology zone of node, and empty if it is not labeled.
+func zoneOf(node *v1.Node) string {
+	if zone, exist := node.Labels[v1.LabelZoneFailureDomainStable]; exist {
+		return zone
+	}
+
+	return node.Labels[v1.LabelZoneFailureDomain]
+}
@@ -0,0 +1,4 @@
+package version
+
+// Version is the version of nacos-k8s-sync, which is set by ldflags when building.
+var Version = "unknown"
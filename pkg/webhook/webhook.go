@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nacos-group/nacos-k8s-sync/pkg/logger"
+	"github.com/nacos-group/nacos-k8s-sync/pkg/model"
+)
+
+// ValidateServicePath is the path of the webhook which validates the annotations of services.
+const ValidateServicePath = "/validate-service"
+
+type Options struct {
+	// Port is the port which the webhook listens on, and zero means that the webhook is disabled.
+	Port int
+
+	CertFile string
+
+	KeyFile string
+}
+
+// Server validates the nacos.io annotations of services before they are created or updated,
+// so that the invalid ones are rejected instead of failing to be synced.
+type Server struct {
+	options Options
+
+	syncOptions model.SyncOptions
+
+	// kubeOptions decide the namespaces whose services are synced.
+	kubeOptions model.KubeOptions
+
+	// nacosNamespace is the nacos namespace which the services are registered into.
+	nacosNamespace string
+
+	serviceInformer cache.SharedIndexInformer
+	serviceLister   lister.ServiceLister
+
+	namespaceInformer cache.SharedIndexInformer
+	namespaceLister   lister.NamespaceLister
+}
+
+func NewServer(options Options, syncOptions model.SyncOptions, kubeOptions model.KubeOptions,
+	nacosNamespace string, kubeClient model.KubeClient) (*Server, error) {
+	if options.CertFile == "" || options.KeyFile == "" {
+		return nil, fmt.Errorf("the cert and key of webhook are required")
+	}
+
+	s := &Server{
+		options:        options,
+		syncOptions:    syncOptions,
+		kubeOptions:    kubeOptions,
+		nacosNamespace: nacosNamespace,
+	}
+
+	// list the services whose service keys may collide with the validated one
+	s.serviceInformer = kubeClient.ServiceInformerFactory().Core().V1().Services().Informer()
+	s.serviceLister = kubeClient.ServiceInformerFactory().Core().V1().Services().Lister()
+	// list namespaces whose annotations are inherited by services
+	s.namespaceInformer = kubeClient.InformerFactory().Core().V1().Namespaces().Informer()
+	s.namespaceLister = kubeClient.InformerFactory().Core().V1().Namespaces().Lister()
+
+	return s, nil
+}
+
+func (s *Server) Run(stop <-chan struct{}) {
+	if !cache.WaitForCacheSync(stop, s.serviceInformer.HasSynced, s.namespaceInformer.HasSynced) {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidateServicePath, s.serveValidateService)
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", s.options.Port),
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-stop
+		if err := server.Close(); err != nil {
+			logger.Errorf("Close webhook server fail, err %v.", err)
+		}
+	}()
+
+	logger.Infof("Serve webhook on port %d.", s.options.Port)
+	if err := server.ListenAndServeTLS(s.options.CertFile, s.options.KeyFile); err != http.ErrServerClosed {
+		logger.Errorf("Serve webhook fail, err %v.", err)
+	}
+}
+
+func (s *Server) serveValidateService(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+	if err := s.validate(review.Request); err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+	}
+	review.Request = nil
+	review.Response = response
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		logger.Errorf("Write admission response fail, err %v.", err)
+	}
+}
+
+func (s *Server) validate(request *admissionv1.AdmissionRequest) error {
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return nil
+	}
+
+	var service v1.Service
+	if err := json.Unmarshal(request.Object.Raw, &service); err != nil {
+		return fmt.Errorf("decode service fail, err %v", err)
+	}
+	// The namespace is absent in the object of the request which creates it.
+	if service.Namespace == "" {
+		service.Namespace = request.Namespace
+	}
+
+	serviceInfo, synced, err := s.serviceInfoOf(&service)
+	if err != nil {
+		return fmt.Errorf("invalid nacos annotations of service (%s:%s), err %v", service.Name, service.Namespace, err)
+	}
+	if !synced || !s.serviceKeyKnown() {
+		return nil
+	}
+
+	return s.checkCollision(&service, serviceInfo.ServiceKey)
+}
+
+// serviceKeyKnown returns whether the service keys are generated only from the annotations and flags
+// known by webhook. The sync rules in nacos config, NacosServiceSync and ServiceExport are not watched
+// by webhook, and they may change the service keys, so the collisions are left to the controller.
+func (s *Server) serviceKeyKnown() bool {
+	return s.syncOptions.RulesDataID == "" && !s.syncOptions.ServiceSyncCRD && !s.syncOptions.MCS
+}
+
+// serviceInfoOf generates the service info in the same way as syncing it, and returns false if the
+// service is not annotated to be synced or its namespace is not watched.
+func (s *Server) serviceInfoOf(service *v1.Service) (model.ServiceInfo, bool, error) {
+	if !s.kubeOptions.NamespaceWatched(service.Namespace) {
+		return model.ServiceInfo{}, false, nil
+	}
+
+	var defaults map[string]string
+	if namespace, err := s.namespaceLister.Get(service.Namespace); err == nil {
+		defaults = model.NamespaceDefaults(namespace)
+	}
+	inherited, err := model.InheritNamespaceDefaults(service, defaults)
+	if err != nil {
+		return model.ServiceInfo{}, false, err
+	}
+
+	if !model.ShouldServiceSync(inherited) {
+		return model.ServiceInfo{}, false, nil
+	}
+
+	serviceInfo, err := model.GenerateServiceInfo(inherited, s.syncOptions)
+	if err != nil {
+		return model.ServiceInfo{}, false, err
+	}
+
+	// The namespace of syncer is always denoted by empty, so that the service key is unique.
+	if serviceInfo.Namespace == s.nacosNamespace {
+		serviceInfo.Namespace = ""
+	}
+	return serviceInfo, true, nil
+}
+
+// checkCollision returns an error if the other synced service is registered as the same nacos service.
+func (s *Server) checkCollision(service *v1.Service, key model.ServiceKey) error {
+	services, err := s.serviceLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	for _, other := range services {
+		if other.Namespace == service.Namespace && other.Name == service.Name {
+			continue
+		}
+		// The invalid services are never synced, so they can not collide with others.
+		otherInfo, synced, err := s.serviceInfoOf(other)
+		if err != nil || !synced {
+			continue
+		}
+		if otherInfo.ServiceKey == key {
+			return fmt.Errorf("nacos service (%s:%s) is already synced from service (%s:%s)",
+				key.ServiceName, key.Group, other.Name, other.Namespace)
+		}
+	}
+
+	return nil
+}